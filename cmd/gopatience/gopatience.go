@@ -0,0 +1,50 @@
+package main
+
+import (
+	"fmt"
+	"github.com/jamesboehmer/gopatience/pkg/games/solitaire"
+	"os"
+)
+
+const usage = `usage: gopatience saves upgrade [file ...]
+
+Rewrites klondike saves from older versions in the current format, keeping a copy of each original with a .bak
+suffix.  With no files, upgrades ~/.gopatience/klondike.save.  Set GOPATIENCE_SIGNING_KEY to sign the upgraded saves.`
+
+func upgradeSaves(paths []string) int {
+	if len(paths) == 0 {
+		path, err := solitaire.DefaultKlondikeSavePath()
+		if err != nil {
+			fmt.Fprintln(os.Stderr, err)
+			return 1
+		}
+		paths = append(paths, path)
+	}
+	status := 0
+	for _, path := range paths {
+		version, err := solitaire.UpgradeKlondikeFile(path)
+		switch {
+		case err != nil:
+			fmt.Fprintf(os.Stderr, "%s: %s\n", path, err)
+			status = 1
+		case version == solitaire.KlondikeSaveVersion:
+			fmt.Printf("%s: already at version %d\n", path, version)
+		default:
+			fmt.Printf("%s: upgraded from version %d to %d, original saved as %s.bak\n",
+				path, version, solitaire.KlondikeSaveVersion, path)
+		}
+	}
+	return status
+}
+
+func main() {
+	if key := os.Getenv("GOPATIENCE_SIGNING_KEY"); key != "" {
+		solitaire.SaveSigningKey = []byte(key)
+	}
+	args := os.Args[1:]
+	if len(args) < 2 || args[0] != "saves" || args[1] != "upgrade" {
+		fmt.Fprintln(os.Stderr, usage)
+		os.Exit(2)
+	}
+	os.Exit(upgradeSaves(args[2:]))
+}
@@ -1,18 +1,109 @@
 package main
 
 import (
+	"errors"
 	"fmt"
 	"github.com/jamesboehmer/gopatience/internal/cmd"
+	"github.com/jamesboehmer/gopatience/pkg/cards"
+	"github.com/jamesboehmer/gopatience/pkg/cards/suit"
 	"github.com/jamesboehmer/gopatience/pkg/games/solitaire"
+	"os"
+	"path/filepath"
+	"runtime"
+	"strconv"
+	"strings"
+	"time"
 )
 
 type KlondikeCmd struct {
 	cmd.Cmd
 	klondike *solitaire.KlondikeGame
+	slots    *solitaire.SaveSlots
+	autosave string
+	unsaved  bool
+}
+
+const finishDelay = 50 * time.Millisecond
+
+var foundationSuits = []suit.Suit{suit.Spades, suit.Diamonds, suit.Clubs, suit.Hearts}
+
+var suitNames = map[string]suit.Suit{
+	"c": suit.Clubs, "clubs": suit.Clubs,
+	"d": suit.Diamonds, "diamonds": suit.Diamonds,
+	"s": suit.Spades, "spades": suit.Spades,
+	"h": suit.Hearts, "hearts": suit.Hearts,
 }
 
 func (cmd *KlondikeCmd) printGame() {
-	fmt.Println("print game...")
+	k := cmd.klondike
+	if k.Unranked {
+		fmt.Printf("Score: %d (unranked)\n", k.Score)
+	} else {
+		fmt.Printf("Score: %d\n", k.Score)
+	}
+	fmt.Printf("Stock: %d\n", k.Stock.Remaining())
+	if len(k.Waste) > 0 {
+		fmt.Printf("Waste: [%s]\n", &k.Waste[len(k.Waste)-1])
+	} else {
+		fmt.Println("Waste: []")
+	}
+	var foundation []string
+	for _, suit := range foundationSuits {
+		pile := k.Foundation.Piles[suit]
+		if len(pile) > 0 {
+			foundation = append(foundation, fmt.Sprintf("%-3s", &pile[len(pile)-1]))
+		} else {
+			foundation = append(foundation, fmt.Sprintf("[%s]", suit))
+		}
+	}
+	fmt.Printf("Foundation: %s\n", strings.Join(foundation, "  "))
+	fmt.Println("Tableau:")
+
+	height := 0
+	var header, rule []string
+	for pileNum, pile := range k.Tableau.Piles {
+		header = append(header, fmt.Sprintf("%-3d", pileNum))
+		rule = append(rule, "---")
+		if len(pile) > height {
+			height = len(pile)
+		}
+	}
+	fmt.Println(strings.TrimRight(strings.Join(header, "  "), " "))
+	fmt.Println(strings.Join(rule, "  "))
+	for cardNum := 0; cardNum < height || cardNum == 0; cardNum++ {
+		var row []string
+		for _, pile := range k.Tableau.Piles {
+			switch {
+			case len(pile) == 0 && cardNum == 0:
+				row = append(row, "[ ]")
+			case cardNum >= len(pile):
+				row = append(row, "   ")
+			case !pile[cardNum].Revealed:
+				row = append(row, "#  ")
+			default:
+				row = append(row, fmt.Sprintf("%-3s", pile[cardNum]))
+			}
+		}
+		fmt.Println(strings.TrimRight(strings.Join(row, "  "), " "))
+	}
+	fmt.Println()
+}
+
+func (cmd *KlondikeCmd) setPrompt(command string, arg string) {
+	cmd.CommandPrompt = fmt.Sprintf("klondike[%s]> ", strings.TrimSpace(command+" "+arg))
+}
+
+// parseInts converts every space-separated argument to an int
+func parseInts(args []string) ([]int, error) {
+	var ints []int
+	for _, arg := range args {
+		i, err := strconv.Atoi(arg)
+		if err != nil {
+			return nil, fmt.Errorf("invalid number: %s", arg)
+		}
+		ints = append(ints, i)
+	}
+	return ints, nil
 }
 
 func (cmd *KlondikeCmd) doQuit(_ string) (bool, error) {
@@ -21,83 +112,412 @@ func (cmd *KlondikeCmd) doQuit(_ string) (bool, error) {
 
 func (cmd *KlondikeCmd) doDeal(_ string) (bool, error) {
 	cmd.CommandPrompt = fmt.Sprintf("klondike[deal]> ")
-	return false, nil
+	return false, cmd.klondike.Deal()
 }
 
-func (cmd *KlondikeCmd) doNew(_ string) (bool, error) {
-	cmd.CommandPrompt = fmt.Sprintf("klondike[new]> ")
+func (cmd *KlondikeCmd) doNew(arg string) (bool, error) {
+	cmd.setPrompt("new", arg)
+	// the player's settings carry over to the new deal, except that a deal code brings its own autoplay
+	autoPlay, destinations := cmd.klondike.AutoPlay, cmd.klondike.Destinations
+	var game *solitaire.KlondikeGame
+	switch fields := strings.Fields(arg); len(fields) {
+	case 0:
+		game = solitaire.NewKlondikeGame()
+	case 1:
+		var err error
+		if game, err = solitaire.NewKlondikeGameFromCode(fields[0]); err != nil {
+			return false, err
+		}
+		autoPlay = game.AutoPlay
+	default:
+		deck, err := cards.ParseDeck(arg)
+		if err != nil {
+			return false, err
+		}
+		if game, err = solitaire.NewKlondikeGameFromDeck(deck); err != nil {
+			return false, err
+		}
+	}
+	game.AutoPlay, game.Destinations = autoPlay, destinations
+	cmd.klondike = game
+	return cmd.doCode("")
+}
+
+func (cmd *KlondikeCmd) doCode(_ string) (bool, error) {
+	code, err := cmd.klondike.DealCode()
+	if err != nil {
+		return false, err
+	}
+	fmt.Printf("deal code: %s\n", code)
 	return false, nil
 }
 
-func (cmd *KlondikeCmd) doWaste(_ string) (bool, error) {
-	cmd.CommandPrompt = fmt.Sprintf("klondike[waste]> ")
+func (cmd *KlondikeCmd) doDump(_ string) (bool, error) {
+	return false, cmd.klondike.Observation().Write(os.Stdout)
+}
+
+func (cmd *KlondikeCmd) doWaste(arg string) (bool, error) {
+	cmd.setPrompt("waste", arg)
+	destinations, err := parseInts(strings.Fields(arg))
+	if err != nil {
+		return false, err
+	}
+	return false, cmd.klondike.SelectWaste(destinations...)
+}
+
+func (cmd *KlondikeCmd) doFoundation(arg string) (bool, error) {
+	cmd.setPrompt("foundation", arg)
+	args := strings.Fields(arg)
+	if len(args) == 0 {
+		return false, errors.New("usage: foundation <c|d|s|h> [<tableau pile num>]")
+	}
+	suit, found := suitNames[strings.ToLower(args[0])]
+	if !found {
+		return false, fmt.Errorf("invalid suit: %s", args[0])
+	}
+	destinations, err := parseInts(args[1:])
+	if err != nil {
+		return false, err
+	}
+	return false, cmd.klondike.SelectFoundation(suit, destinations...)
+}
+
+// savePath returns the file named by arg, or the default save file if there isn't one.
+func savePath(arg string) (string, error) {
+	if path := strings.TrimSpace(arg); path != "" {
+		return path, nil
+	}
+	return solitaire.DefaultKlondikeSavePath()
+}
+
+func (cmd *KlondikeCmd) doSave(arg string) (bool, error) {
+	cmd.setPrompt("save", arg)
+	if name := strings.TrimSpace(arg); solitaire.IsSlotName(name) {
+		if err := cmd.slots.Save(name, cmd.klondike); err != nil {
+			return false, err
+		}
+		fmt.Printf("saved to slot %s\n", name)
+		return false, nil
+	}
+	path, err := savePath(arg)
+	if err != nil {
+		return false, err
+	}
+	if err := cmd.klondike.SaveFile(path); err != nil {
+		return false, err
+	}
+	fmt.Printf("saved to %s\n", path)
 	return false, nil
 }
 
-func (cmd *KlondikeCmd) doFoundation(_ string) (bool, error) {
-	cmd.CommandPrompt = fmt.Sprintf("klondike[foundation]> ")
+func (cmd *KlondikeCmd) doLoad(arg string) (bool, error) {
+	cmd.setPrompt("load", arg)
+	var game *solitaire.KlondikeGame
+	if name := strings.TrimSpace(arg); solitaire.IsSlotName(name) {
+		loaded, err := cmd.slots.Load(name)
+		if err != nil {
+			return false, err
+		}
+		game = loaded
+	} else {
+		path, err := savePath(arg)
+		if err != nil {
+			return false, err
+		}
+		if game, err = solitaire.LoadKlondikeFile(path); err != nil {
+			return false, err
+		}
+	}
+	cmd.klondike = game
 	return false, nil
 }
 
-func (cmd *KlondikeCmd) doSave(_ string) (bool, error) {
-	cmd.CommandPrompt = fmt.Sprintf("klondike[save]> ")
+func (cmd *KlondikeCmd) doSaves(_ string) (bool, error) {
+	cmd.CommandPrompt = fmt.Sprintf("klondike[saves]> ")
+	slots, err := cmd.slots.List()
+	if err != nil {
+		return false, err
+	}
+	if len(slots) == 0 {
+		fmt.Println("no saved games")
+		return false, nil
+	}
+	fmt.Printf("%-16s  %-8s  %5s  %5s  %4s  %s\n", "slot", "variant", "score", "moves", "done", "last played")
+	for _, slot := range slots {
+		ranking := ""
+		if slot.Unranked {
+			ranking = "  unranked"
+		}
+		fmt.Printf("%-16s  %-8s  %5d  %5d  %3d%%  %s%s\n", slot.Name, slot.Variant, slot.Score, slot.Moves,
+			slot.Completion, slot.LastPlayed.Local().Format("2006-01-02 15:04"), ranking)
+	}
+	fmt.Println()
 	return false, nil
 }
 
-func (cmd *KlondikeCmd) doLoad(_ string) (bool, error) {
-	cmd.CommandPrompt = fmt.Sprintf("klondike[load]> ")
+func (cmd *KlondikeCmd) doDelete(arg string) (bool, error) {
+	cmd.setPrompt("delete", arg)
+	name := strings.TrimSpace(arg)
+	if name == "" {
+		return false, errors.New("usage: delete <slot>")
+	}
+	if err := cmd.slots.Delete(name); err != nil {
+		return false, err
+	}
+	fmt.Printf("deleted slot %s\n", name)
 	return false, nil
 }
 
 func (cmd *KlondikeCmd) doSolve(_ string) (bool, error) {
 	cmd.CommandPrompt = fmt.Sprintf("klondike[solve]> ")
+	_, err := cmd.klondike.Finish(func(move solitaire.Move) {
+		cmd.printGame()
+		fmt.Println(move)
+		time.Sleep(finishDelay)
+	})
+	return false, err
+}
+
+func (cmd *KlondikeCmd) doSolver(arg string) (bool, error) {
+	cmd.setPrompt("solver", arg)
+	solver := solitaire.Solver{
+		Hidden:  strings.TrimSpace(arg) == "hidden",
+		Workers: runtime.NumCPU(),
+		Progress: func(progress solitaire.SolverProgress) {
+			fmt.Printf("\rsearching... %d positions, %d moves deep, %d cards home",
+				progress.Nodes, progress.Depth, progress.BestFoundation)
+		},
+	}
+	ctx := cmd.Context()
+	solution := solver.SolveContext(ctx, cmd.klondike)
+	if ctx.Err() != nil {
+		fmt.Printf("\ninterrupted, %s after %d positions\n", solution.Result, solution.Nodes)
+	} else {
+		fmt.Printf("\n%s after %d positions\n", solution.Result, solution.Nodes)
+	}
+	if solution.Result == solitaire.SolverUnknown && solution.Unseen {
+		fmt.Println("every line left needs a face-down or unseen stock card; use 'solver' without 'hidden' to look at them")
+	}
+	fmt.Print(solution.Script())
+	return false, nil
+}
+
+func (cmd *KlondikeCmd) doAutoPlay(arg string) (bool, error) {
+	cmd.setPrompt("autoplay", arg)
+	switch strings.TrimSpace(arg) {
+	case "on":
+		cmd.klondike.AutoPlay = true
+		cmd.klondike.PlaySafe()
+	case "off":
+		cmd.klondike.AutoPlay = false
+	case "":
+		cmd.klondike.AutoPlay = !cmd.klondike.AutoPlay
+		if cmd.klondike.AutoPlay {
+			cmd.klondike.PlaySafe()
+		}
+	default:
+		return false, errors.New("usage: autoplay [on|off]")
+	}
+	if cmd.klondike.AutoPlay {
+		fmt.Println("autoplay is on")
+	} else {
+		fmt.Println("autoplay is off")
+	}
+	return false, nil
+}
+
+func (cmd *KlondikeCmd) doFit(arg string) (bool, error) {
+	cmd.setPrompt("fit", arg)
+	if arg = strings.TrimSpace(arg); arg != "" {
+		policy, err := solitaire.ParseDestinationPolicy(arg)
+		if err != nil {
+			return false, errors.New("usage: fit [best|first]")
+		}
+		cmd.klondike.Destinations = policy
+	}
+	fmt.Printf("cards go to the %s fit\n", cmd.klondike.Destinations)
 	return false, nil
 }
 
 func (cmd *KlondikeCmd) doUndo(_ string) (bool, error) {
 	cmd.CommandPrompt = fmt.Sprintf("klondike[undo]> ")
+	return false, cmd.klondike.Undo()
+}
+
+func (cmd *KlondikeCmd) doRewind(_ string) (bool, error) {
+	cmd.CommandPrompt = fmt.Sprintf("klondike[rewind]> ")
+	if cmd.klondike.UndoToDecision() == 0 {
+		return false, errors.New("nothing to undo")
+	}
 	return false, nil
 }
 
-func (cmd *KlondikeCmd) doTableau(_ string) (bool, error) {
-	cmd.CommandPrompt = fmt.Sprintf("klondike[tableau]> ")
+func (cmd *KlondikeCmd) doHint(_ string) (bool, error) {
+	cmd.CommandPrompt = fmt.Sprintf("klondike[hint]> ")
+	hint, err := cmd.klondike.Hint()
+	if err != nil {
+		return false, err
+	}
+	fmt.Println(hint)
 	return false, nil
 }
 
+func (cmd *KlondikeCmd) doTableau(arg string) (bool, error) {
+	cmd.setPrompt("tableau", arg)
+	args, err := parseInts(strings.Fields(arg))
+	if err != nil {
+		return false, err
+	}
+	if len(args) == 0 {
+		return false, errors.New("usage: tableau <from_pile> [<card_num> [to_pile]]")
+	}
+	return false, cmd.klondike.SelectTableau(args[0], args[1:]...)
+}
+
+// changes wraps a command that can change the game, so the game is autosaved after it runs.
+func (cmd *KlondikeCmd) changes(do func(string) (bool, error)) func(string) (bool, error) {
+	return func(arg string) (bool, error) {
+		cmd.unsaved = true
+		return do(arg)
+	}
+}
+
+// save writes the game to the autosave file if it has changed since it was last saved.
+func (cmd *KlondikeCmd) save() {
+	if !cmd.unsaved || cmd.autosave == "" {
+		return
+	}
+	if err := cmd.klondike.SaveFile(cmd.autosave); err != nil {
+		fmt.Printf("*** autosave failed: %s ***\n", err)
+		return
+	}
+	cmd.unsaved = false
+}
+
+// resume picks up the game from the autosave file, or one of its backups if it's corrupt, and deals a new game if
+// there's nothing to pick up.
+func (cmd *KlondikeCmd) resume() {
+	cmd.klondike = solitaire.NewKlondikeGame()
+	if path, err := solitaire.DefaultKlondikeSavePath(); err == nil {
+		cmd.resumeFile(path)
+	}
+}
+
+// resumeFile picks up the game from path, the way resume does.  A save that can't be read is moved aside to
+// path.unreadable before anything is autosaved over it, and if it can't be moved, the game isn't autosaved at all.
+func (cmd *KlondikeCmd) resumeFile(path string) {
+	game, from, err := solitaire.RecoverKlondikeFile(path, solitaire.DefaultSaveBackups)
+	switch {
+	case os.IsNotExist(err):
+		cmd.autosave = path
+	case err != nil:
+		fmt.Printf("*** could not load %s: %s ***\n", path, err)
+		if err := os.Rename(path, path+".unreadable"); err != nil {
+			fmt.Printf("*** could not move it aside, so this game won't be autosaved: %s ***\n", err)
+			return
+		}
+		fmt.Printf("*** moved it to %s.unreadable ***\n", path)
+		cmd.autosave = path
+	default:
+		cmd.klondike = game
+		cmd.autosave = path
+		if from != path {
+			fmt.Printf("*** %s was damaged, recovered the game from %s ***\n", path, from)
+		}
+	}
+}
+
 func (cmd *KlondikeCmd) Init() *KlondikeCmd {
+	cmd.PreLoop = cmd.printGame
+	cmd.PreCmd = cmd.preCmd
 	cmd.PostCmd = cmd.postCmd
+	cmd.PostLoop = cmd.save
 	cmd.LastCmd = ""
 	cmd.CommandPrompt = "klondike> "
 	cmd.FunctionMap = map[string]func(string) (bool, error){
-		"d":          cmd.doDeal,
-		"deal":       cmd.doDeal,
-		"w":          cmd.doWaste,
-		"waste":      cmd.doWaste,
-		"n":          cmd.doNew,
-		"new":        cmd.doNew,
-		"t":          cmd.doTableau,
-		"tableau":    cmd.doTableau,
-		"f":          cmd.doFoundation,
-		"foundation": cmd.doFoundation,
+		"d":          cmd.changes(cmd.doDeal),
+		"deal":       cmd.changes(cmd.doDeal),
+		"w":          cmd.changes(cmd.doWaste),
+		"waste":      cmd.changes(cmd.doWaste),
+		"n":          cmd.changes(cmd.doNew),
+		"new":        cmd.changes(cmd.doNew),
+		"code":       cmd.doCode,
+		"t":          cmd.changes(cmd.doTableau),
+		"tableau":    cmd.changes(cmd.doTableau),
+		"f":          cmd.changes(cmd.doFoundation),
+		"foundation": cmd.changes(cmd.doFoundation),
 		"s":          cmd.doSave,
 		"save":       cmd.doSave,
-		"l":          cmd.doLoad,
-		"load":       cmd.doLoad,
-		"solve":      cmd.doSolve,
-		"u":          cmd.doUndo,
-		"undo":       cmd.doUndo,
+		"l":          cmd.changes(cmd.doLoad),
+		"load":       cmd.changes(cmd.doLoad),
+		"saves":      cmd.doSaves,
+		"delete":     cmd.doDelete,
+		"solve":      cmd.changes(cmd.doSolve),
+		"solver":     cmd.doSolver,
+		"h":          cmd.doHint,
+		"hint":       cmd.doHint,
+		"a":          cmd.changes(cmd.doAutoPlay),
+		"autoplay":   cmd.changes(cmd.doAutoPlay),
+		"fit":        cmd.changes(cmd.doFit),
+		"u":          cmd.changes(cmd.doUndo),
+		"undo":       cmd.changes(cmd.doUndo),
+		"r":          cmd.changes(cmd.doRewind),
+		"rewind":     cmd.changes(cmd.doRewind),
 		"q":          cmd.doQuit,
 		"quit":       cmd.doQuit,
+		"_dump":      cmd.doDump,
+	}
+	cmd.resume()
+	if slots, err := solitaire.DefaultSaveSlots(); err == nil {
+		cmd.slots = slots
+	} else {
+		cmd.slots = &solitaire.SaveSlots{Dir: filepath.Join(".gopatience", "saves")}
 	}
-	cmd.klondike = solitaire.NewKlondikeGame()
 	return cmd
 }
 
+func (cmd *KlondikeCmd) preCmd(line string) string {
+	cmd.Error = nil
+	return line
+}
+
 func (cmd *KlondikeCmd) postCmd(stop bool, line string) bool {
-	fmt.Println("KlondikeCmd.postCmd")
+	cmd.save()
+	if !stop {
+		cmd.printGame()
+		var moveError *solitaire.MoveError
+		if errors.As(cmd.Error, &moveError) {
+			fmt.Printf("*** no fit for %s ***\n", &moveError.Card)
+			for _, rejection := range moveError.Rejections {
+				fmt.Printf("    %s\n", rejection)
+			}
+		} else if cmd.Error != nil {
+			fmt.Printf("*** %s ***\n", describe(cmd.Error))
+		}
+		if lost, reason := cmd.klondike.IsLost(); lost {
+			fmt.Printf("*** game over: %s ***\n", reason)
+			fmt.Println("type 'new' for a new game, or 'rewind' to undo to the last decision point")
+		}
+	}
 	return stop
 }
 
+// describe says what went wrong in words a player can act on, where the game's error alone wouldn't.
+func describe(err error) string {
+	switch {
+	case errors.Is(err, solitaire.ErrNotSolvable):
+		return "solve only works once the stock and waste are empty and every tableau card is face up"
+	case errors.Is(err, solitaire.ErrNoCardsRemaining):
+		return "there are no cards left to deal"
+	case errors.Is(err, solitaire.ErrConcealed):
+		return "that card is still face down"
+	}
+	return err.Error()
+}
+
 func main() {
+	if key := os.Getenv("GOPATIENCE_SIGNING_KEY"); key != "" {
+		solitaire.SaveSigningKey = []byte(key)
+	}
 	new(KlondikeCmd).Init().CommandLoop()
 }
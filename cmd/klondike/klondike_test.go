@@ -0,0 +1,40 @@
+package main
+
+import (
+	"github.com/jamesboehmer/gopatience/pkg/games/solitaire"
+	"os"
+	"path/filepath"
+	"testing"
+)
+
+func TestKlondikeCmd_ResumeUnreadable(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "klondike.json")
+	if err := os.WriteFile(path, []byte("not a game"), 0644); err != nil {
+		t.Fatal(err)
+	}
+	cmd := &KlondikeCmd{klondike: solitaire.NewKlondikeGame()}
+	cmd.resumeFile(path)
+	if cmd.autosave != path {
+		t.Errorf("Expected to autosave to %s once the unreadable save is moved aside, got %q", path, cmd.autosave)
+	}
+	if data, err := os.ReadFile(path + ".unreadable"); err != nil || string(data) != "not a game" {
+		t.Errorf("The unreadable save should have been moved aside intact, got %q, %v", data, err)
+	}
+
+	// when it can't be moved aside, nothing may be written over it
+	if err := os.WriteFile(path, []byte("still not a game"), 0644); err != nil {
+		t.Fatal(err)
+	}
+	if err := os.Remove(path + ".unreadable"); err != nil {
+		t.Fatal(err)
+	}
+	if err := os.MkdirAll(filepath.Join(path+".unreadable", "full"), 0755); err != nil {
+		t.Fatal(err)
+	}
+	cmd = &KlondikeCmd{klondike: solitaire.NewKlondikeGame(), unsaved: true}
+	cmd.resumeFile(path)
+	cmd.save()
+	if data, err := os.ReadFile(path); err != nil || string(data) != "still not a game" {
+		t.Errorf("An unreadable save that couldn't be moved aside should not be autosaved over, got %q, %v", data, err)
+	}
+}
@@ -2,9 +2,12 @@ package cmd
 
 import (
 	"bufio"
+	"context"
 	"errors"
 	"fmt"
+	"io"
 	"os"
+	"os/signal"
 	"strings"
 )
 
@@ -17,6 +20,16 @@ type Cmd struct {
 	PreCmd        func(string) string
 	PreLoop       func()
 	PostLoop      func()
+	ctx           context.Context
+}
+
+// Context returns a context that's cancelled if the user hits ctrl-c while the current command is running, so long
+// commands can stop early.
+func (cmd *Cmd) Context() context.Context {
+	if cmd.ctx == nil {
+		return context.Background()
+	}
+	return cmd.ctx
 }
 
 func (cmd *Cmd) preLoop() {
@@ -104,20 +117,51 @@ func (cmd *Cmd) init() {
 	}
 }
 
+// readLines sends every line read from r down the channel, and closes it at the end of the input.
+func readLines(r io.Reader) <-chan string {
+	lines := make(chan string)
+	go func() {
+		defer close(lines)
+		reader := bufio.NewReader(r)
+		for {
+			line, err := reader.ReadString('\n')
+			if len(line) > 0 {
+				lines <- line
+			}
+			if err != nil {
+				return
+			}
+		}
+	}()
+	return lines
+}
+
+// CommandLoop reads and runs commands until one of them stops the loop, the input ends, or the user hits ctrl-c at the
+// prompt.  Either way PostLoop runs before it returns.  Hitting ctrl-c while a command is running cancels its Context
+// instead, and the loop carries on once the command returns.
 func (cmd *Cmd) CommandLoop() {
 	cmd.init()
-	reader := bufio.NewReader(os.Stdin)
+	lines := readLines(os.Stdin)
+	interrupts := make(chan os.Signal, 1)
+	signal.Notify(interrupts, os.Interrupt)
+	defer signal.Stop(interrupts)
 	stopLooping := false
 
 	cmd.PreLoop()
 	for !stopLooping {
 		fmt.Print(cmd.CommandPrompt)
-		line, err := reader.ReadString('\n')
-		if err != nil {
-			os.Exit(0)
+		var line string
+		var ok bool
+		select {
+		case line, ok = <-lines:
+		case <-interrupts:
+		}
+		if !ok {
+			fmt.Println()
+			break
 		}
 		line = cmd.PreCmd(strings.TrimSpace(line))
-		stop, err := cmd.OneCmd(line)
+		stop, err := cmd.runCmd(line, interrupts)
 		if err != nil {
 			cmd.Error = err
 		}
@@ -125,3 +169,21 @@ func (cmd *Cmd) CommandLoop() {
 	}
 	cmd.PostLoop()
 }
+
+// runCmd runs one command with a Context that's cancelled by the first interrupt that arrives while it runs.
+func (cmd *Cmd) runCmd(line string, interrupts <-chan os.Signal) (bool, error) {
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+	finished := make(chan bool)
+	defer close(finished)
+	go func() {
+		select {
+		case <-interrupts:
+			cancel()
+		case <-finished:
+		}
+	}()
+	cmd.ctx = ctx
+	defer func() { cmd.ctx = nil }()
+	return cmd.OneCmd(line)
+}
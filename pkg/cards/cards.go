@@ -2,11 +2,13 @@ package cards
 
 import (
 	"errors"
+	"fmt"
 	"github.com/jamesboehmer/gopatience/pkg/cards/pip"
 	"github.com/jamesboehmer/gopatience/pkg/cards/suit"
 	"math/rand"
 	"strings"
 	"time"
+	"unicode"
 )
 
 type Deck struct {
@@ -23,8 +25,13 @@ type Card struct {
 }
 
 func (deck *Deck) Shuffle() *Deck {
-	rand.Seed(time.Now().UnixNano())
-	rand.Shuffle(len(deck.Cards), func(i, j int) { deck.Cards[i], deck.Cards[j] = deck.Cards[j], deck.Cards[i] })
+	return deck.ShuffleSeed(time.Now().UnixNano())
+}
+
+// ShuffleSeed shuffles the deck with its own random source, so the same seed always gives the same order for a new deck.
+func (deck *Deck) ShuffleSeed(seed int64) *Deck {
+	random := rand.New(rand.NewSource(seed))
+	random.Shuffle(len(deck.Cards), func(i, j int) { deck.Cards[i], deck.Cards[j] = deck.Cards[j], deck.Cards[i] })
 	deck.IsShuffled = true
 	return deck
 }
@@ -92,8 +99,8 @@ func NewDeck(numDecks int, numJokers int) *Deck {
 	var cards []Card
 
 	for deckNum := 0; deckNum < numDecks; deckNum++ {
-		for _, suit := range suit.Suits {
-			for _, pip := range pip.Pips {
+		for _, suit := range suit.All {
+			for _, pip := range pip.All {
 				cards = append(cards, Card{pip, suit, false})
 			}
 		}
@@ -110,10 +117,13 @@ func NewDeck(numDecks int, numJokers int) *Deck {
 func ParseCard(cardString string) (*Card, error) {
 	revealed := true
 	runes := []rune(cardString)
-	if runes[0] == '|' {
+	if len(runes) > 0 && runes[0] == '|' {
 		revealed = false
 		runes = runes[1:]
 	}
+	if len(runes) == 0 {
+		return nil, errors.New("empty card")
+	}
 	if runes[0] == '*' {
 		return &Card{"", "", revealed}, nil
 	}
@@ -128,3 +138,18 @@ func ParseCard(cardString string) (*Card, error) {
 	}
 	return &Card{pip, suit, revealed}, nil
 }
+
+// ParseDeck reads a deck from card strings separated by spaces or commas, in the format ParseCard reads.  The first
+// card listed is the first one dealt.
+func ParseDeck(deckString string) (*Deck, error) {
+	deck := &Deck{NumDecks: 1, IsShuffled: true}
+	tokens := strings.FieldsFunc(deckString, func(r rune) bool { return r == ',' || unicode.IsSpace(r) })
+	for _, token := range tokens {
+		card, err := ParseCard(token)
+		if err != nil {
+			return nil, fmt.Errorf("card %q: %w", token, err)
+		}
+		deck.Cards = append(deck.Cards, *card)
+	}
+	return deck, nil
+}
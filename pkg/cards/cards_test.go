@@ -67,6 +67,23 @@ func TestDeck_Shuffle(t *testing.T) {
 	}
 }
 
+func TestDeck_ShuffleSeed(t *testing.T) {
+	first, second := NewDeck(1, 0).ShuffleSeed(42), NewDeck(1, 0).ShuffleSeed(42)
+	for i := range first.Cards {
+		if first.Cards[i] != second.Cards[i] {
+			t.Fatal("Decks shuffled with the same seed should be in the same order")
+		}
+	}
+	third := NewDeck(1, 0).ShuffleSeed(43)
+	same := true
+	for i := range first.Cards {
+		same = same && first.Cards[i] == third.Cards[i]
+	}
+	if same {
+		t.Error("Decks shuffled with different seeds should be in different orders")
+	}
+}
+
 func TestDeck_Deal_Remaining(t *testing.T) {
 	deck := NewDeck(1, 0)
 	if deck.Remaining() != 52 {
@@ -147,6 +164,11 @@ func TestParseCard(t *testing.T) {
 	if err == nil {
 		t.Error("Bad pip string should have returned an error")
 	}
+	for _, cardString := range []string{"", "|"} {
+		if _, err := ParseCard(cardString); err == nil {
+			t.Errorf("Empty card string %q should have returned an error", cardString)
+		}
+	}
 	card, err := ParseCard("*")
 	if err != nil {
 		t.Error("Joker card should have been parsed")
@@ -179,6 +201,19 @@ func TestParseCard(t *testing.T) {
 	}
 }
 
+func TestParseDeck(t *testing.T) {
+	deck, err := ParseDeck("A♠ 10♥,|K♦\n  2♣")
+	if err != nil {
+		t.Fatal("Deck should have been parsed")
+	}
+	if len(deck.Cards) != 4 || deck.Cards[1].Pip != pip.Ten || deck.Cards[2].Revealed {
+		t.Error("Parsed deck should have the cards in order")
+	}
+	if _, err := ParseDeck("A♠ 10x"); err == nil || err.Error() != `card "10x": invalid suit` {
+		t.Errorf("Bad card should have returned an error, got %v", err)
+	}
+}
+
 func TestSuitColor(t *testing.T) {
 	if suit.Spades.Color() != suit.Black {
 		t.Errorf("%s should be black", suit.Spades)
@@ -23,6 +23,9 @@ var Pips = map[string]Pip{
 	"8": Eight, "9": Nine, "10": Ten, "J": Jack, "Q": Queen, "K": King,
 }
 
+// All is every pip from the ace up to the king, unlike ranging over Pips, which goes in a different order each time.
+var All = []Pip{Ace, Two, Three, Four, Five, Six, Seven, Eight, Nine, Ten, Jack, Queen, King}
+
 func (p Pip) IsFace() bool {
 	return p == Jack || p == Queen || p == King
 }
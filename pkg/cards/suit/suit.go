@@ -15,6 +15,10 @@ const (
 
 var Suits = map[string]Suit{"♠": Spades, "♥": Hearts, "♦": Diamonds, "♣": Clubs}
 
+// All is the four suits in the order they're declared.  Deal codes number cards by their place in it, so changing the
+// order would change what every code deals.
+var All = []Suit{Spades, Hearts, Diamonds, Clubs}
+
 func (suit Suit) Color() Color {
 	if suit == Diamonds || suit == Hearts {
 		return Red
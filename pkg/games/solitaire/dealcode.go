@@ -0,0 +1,120 @@
+package solitaire
+
+import (
+	"encoding/base64"
+	"errors"
+	"fmt"
+	"github.com/jamesboehmer/gopatience/pkg/cards"
+	"github.com/jamesboehmer/gopatience/pkg/cards/pip"
+	"github.com/jamesboehmer/gopatience/pkg/cards/suit"
+	"math/big"
+)
+
+// A deal code is a header byte followed by the deal's place among all 52! orders of a deck, as a fixed-size big-endian
+// number, in URL-safe base64.  The header's high nibble is the code format and its low bits are the game's options.
+const (
+	dealCodeFormat   = 1
+	dealCodeAutoPlay = 1 << 0
+	dealCodeRankSize = 29 // 52! needs 226 bits
+)
+
+// deckIndex numbers the 52 cards of a deck in suit.All and pip.All order.
+var deckIndex = func() map[cards.Card]int {
+	index := make(map[cards.Card]int, 52)
+	for suitNum, cardSuit := range suit.All {
+		for pipNum, cardPip := range pip.All {
+			index[cards.Card{Pip: cardPip, Suit: cardSuit}] = suitNum*len(pip.All) + pipNum
+		}
+	}
+	return index
+}()
+
+// DealCode returns a 40 character code for the order the game was dealt from and its options, which
+// NewKlondikeGameFromCode turns back into the same deal.  Games that weren't dealt from a deck or a seed, like those
+// built from a layout, have no code.
+func (k *KlondikeGame) DealCode() (string, error) {
+	order := k.order
+	if order == nil && k.Seed != 0 {
+		order = cards.NewDeck(1, 0).ShuffleSeed(k.Seed).Cards
+	}
+	if len(order) != 52 {
+		return "", errors.New("the order of this deal isn't known")
+	}
+
+	// the Lehmer code of the order, read as one mixed-radix number
+	remaining := make([]int, 0, 52)
+	for i := 0; i < 52; i++ {
+		remaining = append(remaining, i)
+	}
+	rank := new(big.Int)
+	for position, card := range order {
+		card.Revealed = false
+		index, found := deckIndex[card]
+		if !found {
+			return "", fmt.Errorf("%s is not a klondike card", &card)
+		}
+		digit := -1
+		for i, candidate := range remaining {
+			if candidate == index {
+				digit = i
+				break
+			}
+		}
+		if digit < 0 {
+			return "", fmt.Errorf("%s appears more than once", &card)
+		}
+		remaining = append(remaining[:digit], remaining[digit+1:]...)
+		rank.Mul(rank, big.NewInt(int64(52-position)))
+		rank.Add(rank, big.NewInt(int64(digit)))
+	}
+
+	code := make([]byte, 1+dealCodeRankSize)
+	code[0] = dealCodeFormat << 4
+	if k.AutoPlay {
+		code[0] |= dealCodeAutoPlay
+	}
+	rankBytes := rank.Bytes()
+	copy(code[len(code)-len(rankBytes):], rankBytes)
+	return base64.RawURLEncoding.EncodeToString(code), nil
+}
+
+// NewKlondikeGameFromCode deals the game a DealCode came from, with the same options.
+func NewKlondikeGameFromCode(code string) (*KlondikeGame, error) {
+	deck, autoPlay, err := decodeDealCode(code)
+	if err != nil {
+		return nil, err
+	}
+	game := dealKlondikeGame(*deck)
+	game.AutoPlay = autoPlay
+	return game, nil
+}
+
+func decodeDealCode(code string) (*cards.Deck, bool, error) {
+	data, err := base64.RawURLEncoding.DecodeString(code)
+	if err != nil || len(data) != 1+dealCodeRankSize {
+		return nil, false, errors.New("invalid deal code")
+	}
+	if data[0]>>4 != dealCodeFormat {
+		return nil, false, fmt.Errorf("unsupported deal code format %d", data[0]>>4)
+	}
+	rank := new(big.Int).SetBytes(data[1:])
+
+	// peel the Lehmer code digits off from the last card back
+	digits := make([]int, 52)
+	modulus := new(big.Int)
+	for position := 51; position >= 0; position-- {
+		rank.DivMod(rank, big.NewInt(int64(52-position)), modulus)
+		digits[position] = int(modulus.Int64())
+	}
+	if rank.Sign() != 0 {
+		return nil, false, errors.New("invalid deal code")
+	}
+	deck := cards.NewDeck(1, 0)
+	remaining := append([]cards.Card{}, deck.Cards...)
+	for position, digit := range digits {
+		deck.Cards[position] = remaining[digit]
+		remaining = append(remaining[:digit], remaining[digit+1:]...)
+	}
+	deck.IsShuffled = true
+	return deck, data[0]&dealCodeAutoPlay != 0, nil
+}
@@ -0,0 +1,67 @@
+package solitaire
+
+import (
+	"bytes"
+	"strings"
+	"testing"
+)
+
+func TestKlondikeGame_DealCode(t *testing.T) {
+	for seed := int64(1); seed <= 20; seed++ {
+		k := NewSeededKlondikeGame(seed)
+		k.AutoPlay = seed%2 == 0
+		code, err := k.DealCode()
+		if err != nil {
+			t.Fatalf("Seeded games should have a deal code, got %s", err)
+		}
+		if len(code) != 40 {
+			t.Errorf("Deal codes should be 40 characters, got %q", code)
+		}
+		dealt, err := NewKlondikeGameFromCode(code)
+		if err != nil {
+			t.Fatalf("Deal code %q should be valid, got %s", code, err)
+		}
+		if !dealt.Equal(k, HashOptions{}) || dealt.AutoPlay != k.AutoPlay {
+			t.Errorf("Deal code %q should deal the same game with the same options", code)
+		}
+		if again, _ := dealt.DealCode(); again != code {
+			t.Errorf("A game dealt from a code should have the same code, got %q and %q", code, again)
+		}
+	}
+}
+
+func TestKlondikeGame_DealCodeAfterMoves(t *testing.T) {
+	k := NewSeededKlondikeGame(7)
+	code, _ := k.DealCode()
+	k.Deal()
+	k.Deal()
+	if later, _ := k.DealCode(); later != code {
+		t.Error("The deal code should not change as the game is played")
+	}
+	dealt, _ := NewKlondikeGameFromCode(code)
+	buffer := bytes.Buffer{}
+	dealt.Save(&buffer)
+	if loaded, _ := LoadKlondikeGame(&buffer); loaded == nil {
+		t.Error("A game dealt from a code should load")
+	} else if saved, _ := loaded.DealCode(); saved != code {
+		t.Error("A game dealt from a code should keep its code when it's saved and loaded")
+	}
+	parsed, _ := ParseKlondikeLayout(k.Layout())
+	if _, err := parsed.DealCode(); err == nil {
+		t.Error("Games built from a layout should have no deal code")
+	}
+}
+
+func TestNewKlondikeGameFromCode_Invalid(t *testing.T) {
+	for name, code := range map[string]string{
+		"empty":      "",
+		"not base64": "not a deal code, not a deal code, not a d",
+		"too short":  "EAAAAAAAAAAAAAAAAAAA",
+		"format":     "IA" + strings.Repeat("A", 38),
+		"rank":       "EP" + strings.Repeat("_", 38),
+	} {
+		if _, err := NewKlondikeGameFromCode(code); err == nil {
+			t.Errorf("%s: deal code %q should be invalid", name, code)
+		}
+	}
+}
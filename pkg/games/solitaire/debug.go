@@ -0,0 +1,8 @@
+//go:build debug
+// +build debug
+
+package solitaire
+
+// debugValidation makes every move and undo check that it leaves a valid game valid.  Build with -tags debug to turn
+// it on.
+const debugValidation = true
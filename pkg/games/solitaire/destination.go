@@ -0,0 +1,105 @@
+package solitaire
+
+import (
+	"fmt"
+	"sort"
+)
+
+// A DestinationPolicy chooses where SelectTableau, SelectWaste and SelectFoundation put cards when they have more than
+// one place to try, because no destination was given.
+type DestinationPolicy int
+
+const (
+	// BestFit still tries the foundation first, but then picks the tableau pile whose face-up run is longest, so moved
+	// cards join an existing build rather than start a new one.  Between piles with equal runs it picks the one with
+	// the fewest face-down cards, so as little as possible is buried.  Only kings go into empty columns, and every
+	// empty column is as good as the next, so a king still takes the first one.
+	BestFit DestinationPolicy = iota
+	// FirstFit tries the foundation first and then each tableau pile from 0 up, the way earlier versions did.
+	FirstFit
+)
+
+var destinationPolicyNames = map[DestinationPolicy]string{BestFit: "best", FirstFit: "first"}
+
+func (p DestinationPolicy) String() string {
+	if name, found := destinationPolicyNames[p]; found {
+		return name
+	}
+	return fmt.Sprintf("DestinationPolicy(%d)", int(p))
+}
+
+// ParseDestinationPolicy returns the policy named by its String.
+func ParseDestinationPolicy(name string) (DestinationPolicy, error) {
+	for policy, policyName := range destinationPolicyNames {
+		if policyName == name {
+			return policy, nil
+		}
+	}
+	return BestFit, fmt.Errorf("unknown destination policy %q", name)
+}
+
+// orderMoves puts the legal moves first, in the order the game's destination policy would try them, and leaves the
+// illegal ones after them in their original order.  Foundation moves always come first, whatever the policy, so that
+// a bare "w" or "t 3" from Move.Command replays as the move it was written for.
+func (k *KlondikeGame) orderMoves(moves []Move) []Move {
+	if k.Destinations == FirstFit {
+		return moves
+	}
+	type placedMove struct {
+		move   Move
+		run    int
+		buried int
+	}
+	var home, illegal []Move
+	var legal []placedMove
+	for _, move := range moves {
+		if !k.IsLegal(move) {
+			illegal = append(illegal, move)
+			continue
+		}
+		if move.Type == MoveWasteFoundation || move.Type == MoveTableauFoundation {
+			home = append(home, move)
+			continue
+		}
+		legal = append(legal, placedMove{move: move, run: k.joinsRun(move), buried: k.buries(move)})
+	}
+	sort.SliceStable(legal, func(i, j int) bool {
+		if legal[i].run != legal[j].run {
+			return legal[i].run > legal[j].run
+		}
+		return legal[i].buried < legal[j].buried
+	})
+	ordered := append(make([]Move, 0, len(moves)), home...)
+	for _, placed := range legal {
+		ordered = append(ordered, placed.move)
+	}
+	return append(ordered, illegal...)
+}
+
+// joinsRun counts the face-up cards built in sequence on top of the tableau pile a move builds on, which the moved
+// cards carry on.  An empty column has no run.
+func (k *KlondikeGame) joinsRun(move Move) int {
+	pile := k.Tableau.Piles[move.ToPile]
+	if len(pile) == 0 || !pile[len(pile)-1].Revealed {
+		return 0
+	}
+	run := 1
+	for cardNum := len(pile) - 1; cardNum > 0; cardNum-- {
+		if !pile[cardNum-1].Revealed || !buildsOn(pile[cardNum], pile[cardNum-1]) {
+			break
+		}
+		run++
+	}
+	return run
+}
+
+// buries counts the face-down cards under the tableau pile a move builds on.
+func (k *KlondikeGame) buries(move Move) int {
+	buried := 0
+	for _, card := range k.Tableau.Piles[move.ToPile] {
+		if !card.Revealed {
+			buried++
+		}
+	}
+	return buried
+}
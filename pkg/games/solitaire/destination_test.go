@@ -0,0 +1,135 @@
+package solitaire
+
+import (
+	"bytes"
+	"fmt"
+	"testing"
+)
+
+// newDestinationGame has two red nines to build on, the first over face-down cards, a black six, the given top card
+// on the waste and cards in pile 3, and the hearts up to 4♥ on the foundation.
+func newDestinationGame(t *testing.T, policy DestinationPolicy, waste string, pile3 string) *KlondikeGame {
+	k := parseTestLayout(t, fmt.Sprintf(`
+waste: %s
+foundation: 4♥
+0: |K♠ |Q♠ |J♠ 9♥
+1: 9♦
+2: 6♠
+3: %s
+4:
+5:
+6:
+`, waste, pile3))
+	k.Destinations = policy
+	return k
+}
+
+func TestKlondikeGame_SelectWaste_Destinations(t *testing.T) {
+	for _, test := range []struct {
+		policy   DestinationPolicy
+		waste    string
+		expected Move
+	}{
+		// both nines fit, but only one buries face-down cards
+		{BestFit, "8♠", Move{Type: MoveWasteTableau, ToPile: 1}},
+		{FirstFit, "8♠", Move{Type: MoveWasteTableau, ToPile: 0}},
+		// the foundation always comes first, so a bare "w" means the same move under either policy
+		{BestFit, "5♥", Move{Type: MoveWasteFoundation}},
+		{FirstFit, "5♥", Move{Type: MoveWasteFoundation}},
+	} {
+		k := newDestinationGame(t, test.policy, test.waste, "")
+		if err := k.SelectWaste(); err != nil {
+			t.Fatal(err)
+		}
+		if move := k.History[len(k.History)-1]; move != test.expected {
+			t.Errorf("%s fit for %s: expected %s, got %s", test.policy, test.waste, test.expected, move)
+		}
+	}
+}
+
+func TestKlondikeGame_SelectTableau_Destinations(t *testing.T) {
+	k := newDestinationGame(t, BestFit, "2♣", "8♣")
+	if err := k.SelectTableau(3); err != nil {
+		t.Fatal(err)
+	}
+	if move := k.History[len(k.History)-1]; move.ToPile != 1 {
+		t.Errorf("Expected 8♣ to go on the nine with nothing under it, got %s", move)
+	}
+	// the same rejections as ever when nothing fits
+	k.Undo()
+	if err := k.SelectTableau(2); err == nil {
+		t.Error("6♠ shouldn't fit anywhere")
+	}
+}
+
+func TestMove_Command_Replay(t *testing.T) {
+	games := benchmarkGames()
+	// 5♥ can go home or onto 6♠, and 8♣ can leave pile 3 for either nine
+	games = append(games, newDestinationGame(t, BestFit, "5♥", "|A♣ 8♣"))
+	replayed := map[MoveType]bool{}
+	for _, policy := range []DestinationPolicy{BestFit, FirstFit} {
+		for _, k := range games {
+			k.Destinations = policy
+			for _, move := range k.LegalMoves() {
+				expected, actual := k.Clone(), k.Clone()
+				expected.Apply(move)
+				if err := replayCommand(actual, move.Command()); err != nil {
+					t.Fatalf("%q should replay %s: %s", move.Command(), move, err)
+				}
+				if !actual.Equal(expected, HashOptions{}) || actual.History[len(actual.History)-1] != move {
+					t.Fatalf("%s fit: %q should replay %s, got %s", policy, move.Command(), move,
+						actual.History[len(actual.History)-1])
+				}
+				replayed[move.Type] = true
+			}
+		}
+	}
+	for moveType := MoveDeal; moveType <= MoveFoundationTableau; moveType++ {
+		if !replayed[moveType] {
+			t.Errorf("No %s move was replayed", Move{Type: moveType})
+		}
+	}
+}
+
+func TestKlondikeGame_Destinations_Save(t *testing.T) {
+	k := NewSeededKlondikeGame(5)
+	k.Destinations = FirstFit
+	buffer := bytes.Buffer{}
+	if err := k.Save(&buffer); err != nil {
+		t.Fatal(err)
+	}
+	loaded, err := LoadKlondikeGame(&buffer)
+	if err != nil || loaded.Destinations != FirstFit {
+		t.Errorf("The destination policy should be saved, got %v", err)
+	}
+	if policy, err := ParseDestinationPolicy("best"); err != nil || policy != BestFit {
+		t.Errorf("Expected the best fit policy, got %s %v", policy, err)
+	}
+	if _, err := ParseDestinationPolicy("worst"); err == nil {
+		t.Error("Unknown policies should be refused")
+	}
+}
+
+func TestKlondikeGame_SelectWaste_LongestRun(t *testing.T) {
+	for _, test := range []struct {
+		policy   DestinationPolicy
+		expected int
+	}{
+		// neither nine has face-down cards under it, but only one carries on a run
+		{BestFit, 1},
+		{FirstFit, 0},
+	} {
+		k := parseTestLayout(t, `
+waste: 8♣
+0: 9♥
+1: 10♠ 9♦
+`)
+		k.Destinations = test.policy
+		if err := k.SelectWaste(); err != nil {
+			t.Fatal(err)
+		}
+		if move := k.History[len(k.History)-1]; move.ToPile != test.expected {
+			t.Errorf("%s fit: expected 8♣ to go on tableau %d, got %s", test.policy, test.expected, move)
+		}
+	}
+}
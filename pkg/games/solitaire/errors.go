@@ -0,0 +1,33 @@
+package solitaire
+
+import (
+	"errors"
+	"fmt"
+)
+
+// Errors from the foundation, tableau and game, for matching with errors.Is.  The public methods wrap them with where
+// they happened, e.g. "tableau 9: invalid pile".  A *Rejection or *MoveError is also an ErrNoFit, and can be unpacked
+// with errors.As to see why the cards didn't fit.
+var (
+	ErrNoFit            = errors.New("no fit")
+	ErrConcealed        = errors.New("card is concealed")
+	ErrInvalidPile      = errors.New("invalid pile")
+	ErrInvalidCard      = errors.New("invalid card number")
+	ErrEmptyPile        = errors.New("pile is empty")
+	ErrNoCardsRemaining = errors.New("no cards remaining")
+	ErrNotSolvable      = errors.New("game is not solvable yet")
+	ErrNoMoves          = errors.New("no moves available")
+	ErrIllegalMove      = errors.New("illegal move")
+)
+
+var errNotTopCard = fmt.Errorf("%w: only the top tableau card may be moved to the foundation", ErrIllegalMove)
+
+// located wraps an error from one of the move checks with where it happened.  The checks return their errors bare, so
+// that trying moves stays cheap, and only the public methods pay for saying where.  A *Rejection already says where,
+// so it's returned as it is.
+func located(err error, format string, args ...interface{}) error {
+	if _, isRejection := err.(*Rejection); isRejection || err == nil {
+		return err
+	}
+	return fmt.Errorf(format+": %w", append(args, err)...)
+}
@@ -0,0 +1,69 @@
+package solitaire
+
+import (
+	"errors"
+	"github.com/jamesboehmer/gopatience/pkg/cards/suit"
+	"testing"
+)
+
+// errorsLayout has nothing left to deal, an empty column, and a 9♣ that doesn't fit anywhere.
+const errorsLayout = `
+foundation: K♦ Q♠ 7♣
+0: |K♠ |8♣ |10♣ 9♣
+1:
+2: |A♥ |2♥ |3♥ |4♥ |5♥ |6♥ |7♥ |8♥ |9♥ |10♥ |J♥ |K♥ Q♥
+3: |J♣ |Q♣ K♣
+4:
+5:
+6:
+`
+
+func TestErrors(t *testing.T) {
+	k, err := ParseKlondikeLayout(errorsLayout)
+	if err != nil {
+		t.Fatal(err)
+	}
+	unsolvable := NewSeededKlondikeGame(1)
+	for _, test := range []struct {
+		name     string
+		err      error
+		expected error
+	}{
+		{"invalid pile", k.SelectTableau(9), ErrInvalidPile},
+		{"invalid destination", k.SelectTableau(0, 1, 0), ErrInvalidPile},
+		{"invalid card", k.SelectTableau(0, 5), ErrInvalidCard},
+		{"concealed", k.SelectTableau(0, 0), ErrConcealed},
+		{"empty tableau pile", k.SelectTableau(1), ErrEmptyPile},
+		{"empty waste", k.SelectWaste(), ErrEmptyPile},
+		{"empty foundation", k.SelectFoundation(suit.Hearts), ErrEmptyPile},
+		{"no fit", k.SelectTableau(0), ErrNoFit},
+		{"no cards remaining", k.Deal(), ErrNoCardsRemaining},
+		{"not solvable", unsolvable.Solve(), ErrNotSolvable},
+		{"illegal move", k.Apply(Move{Type: MoveType(99)}), ErrIllegalMove},
+	} {
+		if !errors.Is(test.err, test.expected) {
+			t.Errorf("%s: expected %q, got %v", test.name, test.expected, test.err)
+		}
+	}
+
+	for err, expected := range map[error]string{
+		k.SelectTableau(9):                         "tableau 9: invalid pile",
+		k.SelectTableau(0, 0):                      "tableau 0 card 0: card is concealed",
+		k.SelectFoundation(suit.Hearts):            "♥ foundation: pile is empty",
+		k.Apply(Move{Type: MoveWasteFoundation}):   "waste to foundation: pile is empty",
+		k.Apply(Move{Type: MoveTableauFoundation}): "tableau 0 to foundation: card is concealed",
+		k.Apply(Move{Type: MoveDeal}):              "deal: no cards remaining",
+	} {
+		if err == nil || err.Error() != expected {
+			t.Errorf("Errors should say where they happened, expected %q, got %v", expected, err)
+		}
+	}
+	// the checks behind them leave the wrapping to the public methods, so trying moves stays cheap
+	if k.Tableau.checkGet(9, 0) != ErrInvalidPile || k.checkMove(Move{Type: MoveWasteTableau}) != ErrEmptyPile {
+		t.Error("Move checks should return bare errors")
+	}
+	var moveError *MoveError
+	if err := k.SelectTableau(0); !errors.As(err, &moveError) || !errors.Is(moveError.Rejections[0], ErrNoFit) {
+		t.Errorf("No fit errors should unpack to the rejections, got %v", err)
+	}
+}
@@ -1,7 +1,6 @@
 package solitaire
 
 import (
-	"errors"
 	"github.com/jamesboehmer/gopatience/pkg/cards"
 	"github.com/jamesboehmer/gopatience/pkg/cards/pip"
 	"github.com/jamesboehmer/gopatience/pkg/cards/suit"
@@ -11,6 +10,7 @@ import (
 type Foundation struct {
 	util.Undoable
 	Piles map[suit.Suit][]cards.Card
+	hash  uint64
 }
 
 func NewFoundation(suits []suit.Suit) *Foundation {
@@ -25,51 +25,94 @@ func NewFoundation(suits []suit.Suit) *Foundation {
 func (f *Foundation) undoPut(args ...interface{}) error {
 	suit := args[0].(suit.Suit)
 	f.Piles[suit] = f.Piles[suit][:len(f.Piles[suit])-1]
+	f.hashHeight(suit, len(f.Piles[suit])+1)
 	return nil
 }
 
-func (f *Foundation) Put(card cards.Card) error {
+// fits reports whether the card can go on its suit's pile, by the same rules as checkPut but without saying why not.
+func (f *Foundation) fits(card cards.Card) bool {
+	pile, found := f.Piles[card.Suit]
+	if !card.Revealed || !found {
+		return false
+	}
+	if len(pile) == 0 {
+		return card.Pip == pip.Ace
+	}
+	return PipValue[card.Pip] == PipValue[pile[len(pile)-1].Pip]+1
+}
+
+func (f *Foundation) checkPut(card cards.Card) error {
 	if !card.Revealed {
-		return errors.New("foundation cards must be revealed")
+		return ErrConcealed
 	}
-	pile := f.Piles[card.Suit]
-	if card.Pip == pip.Ace {
-		f.Piles[card.Suit] = append(pile, card)
-		f.UndoStack = append(f.UndoStack, util.UndoAction{Function: f.undoPut, Args: []interface{}{card.Suit}})
-		return nil
-	} else if len(pile) == 0 {
-		return errors.New("the first card on a foundation pile must be an ace")
-	} else {
-		topCard := pile[len(pile)-1]
-		if PipValue[card.Pip] != PipValue[topCard.Pip]+1 {
-			return errors.New("foundation cards must be built sequentially by suit")
-		}
-		f.Piles[card.Suit] = append(pile, card)
-		f.UndoStack = append(f.UndoStack, util.UndoAction{Function: f.undoPut, Args: []interface{}{card.Suit}})
+	pile, found := f.Piles[card.Suit]
+	if !found {
+		return ErrInvalidPile
+	}
+	if f.fits(card) {
 		return nil
 	}
+	rejection := &Rejection{Card: card, Pile: "foundation"}
+	if len(pile) == 0 {
+		rejection.Reasons = append(rejection.Reasons, "only aces on empty piles")
+		return rejection
+	}
+	topCard := pile[len(pile)-1]
+	rejection.Target = &topCard
+	rejection.Reasons = append(rejection.Reasons, "not one rank higher")
+	return rejection
+}
+
+func (f *Foundation) Put(card cards.Card) error {
+	if err := f.checkPut(card); err != nil {
+		return located(err, "%s foundation", card.Suit)
+	}
+	f.Piles[card.Suit] = append(f.Piles[card.Suit], card)
+	f.hashHeight(card.Suit, len(f.Piles[card.Suit])-1)
+	f.UndoStack = append(f.UndoStack, util.UndoAction{Function: f.undoPut, Args: []interface{}{card.Suit}})
+	return nil
 }
 
 func (f *Foundation) undoGet(args ...interface{}) error {
 	card := args[0].(cards.Card)
 	f.Piles[card.Suit] = append(f.Piles[card.Suit], card)
+	f.hashHeight(card.Suit, len(f.Piles[card.Suit])-1)
 	return nil
 }
 
-func (f *Foundation) Get(suit suit.Suit) (*cards.Card, error) {
+// canGet reports whether the suit's top card can be taken off the foundation, by the same rules as checkGet.
+func (f *Foundation) canGet(suit suit.Suit) bool {
+	return len(f.Piles[suit]) > 0
+}
+
+func (f *Foundation) checkGet(suit suit.Suit) error {
 	pile, found := f.Piles[suit]
 	if !found {
-		return nil, errors.New("no such suit")
+		return ErrInvalidPile
 	}
 	if len(pile) == 0 {
-		return nil, errors.New("pile is empty")
+		return ErrEmptyPile
+	}
+	return nil
+}
+
+func (f *Foundation) Get(suit suit.Suit) (*cards.Card, error) {
+	if err := f.checkGet(suit); err != nil {
+		return nil, located(err, "%s foundation", suit)
 	}
+	pile := f.Piles[suit]
 	topCard := pile[len(pile)-1]
 	f.Piles[suit] = pile[:len(pile)-1]
+	f.hashHeight(suit, len(pile))
 	f.UndoStack = append(f.UndoStack, util.UndoAction{Function: f.undoGet, Args: []interface{}{topCard}})
 	return &topCard, nil
 }
 
+// hashHeight updates the hash after the suit's pile changed from the given height.
+func (f *Foundation) hashHeight(suit suit.Suit, from int) {
+	f.hash ^= foundationKey(suit, from) ^ foundationKey(suit, len(f.Piles[suit]))
+}
+
 func (f *Foundation) IsFull() bool {
 	for _, pile := range f.Piles {
 		if len(pile) != 13 {
@@ -78,3 +121,18 @@ func (f *Foundation) IsFull() bool {
 	}
 	return true
 }
+
+// isSafe reports whether card could never be needed on the tableau once it's on the foundation, because both
+// opposite-colored cards one rank lower are already there.
+func (f *Foundation) isSafe(card cards.Card) bool {
+	value := PipValue[card.Pip]
+	if value <= 2 {
+		return true
+	}
+	for suit, pile := range f.Piles {
+		if suit.Color() != card.Suit.Color() && len(pile) < value-1 {
+			return false
+		}
+	}
+	return true
+}
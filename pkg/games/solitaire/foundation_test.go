@@ -109,3 +109,23 @@ func TestFoundation_IsFull(t *testing.T) {
 		t.Error("Foundation should be full.")
 	}
 }
+
+func TestFoundation_isSafe(t *testing.T) {
+	f := NewFoundation([]suit.Suit{suit.Hearts, suit.Diamonds, suit.Clubs, suit.Spades})
+	if !f.isSafe(cards.Card{Pip: pip.Two, Suit: suit.Hearts, Revealed: true}) {
+		t.Error("Aces and twos are always safe")
+	}
+	if f.isSafe(cards.Card{Pip: pip.Three, Suit: suit.Hearts, Revealed: true}) {
+		t.Error("3♥ isn't safe until both black twos are home")
+	}
+	for _, suit := range []suit.Suit{suit.Clubs, suit.Spades} {
+		f.Put(cards.Card{Pip: pip.Ace, Suit: suit, Revealed: true})
+		f.Put(cards.Card{Pip: pip.Two, Suit: suit, Revealed: true})
+	}
+	if !f.isSafe(cards.Card{Pip: pip.Three, Suit: suit.Hearts, Revealed: true}) {
+		t.Error("3♥ is safe once both black twos are home")
+	}
+	if f.isSafe(cards.Card{Pip: pip.Three, Suit: suit.Clubs, Revealed: true}) {
+		t.Error("3♣ isn't safe until both red twos are home")
+	}
+}
@@ -0,0 +1,202 @@
+package solitaire
+
+import (
+	"github.com/jamesboehmer/gopatience/pkg/cards"
+	"github.com/jamesboehmer/gopatience/pkg/cards/suit"
+	"sort"
+	"strings"
+)
+
+// HashOptions chooses which positions Hash and Equal treat as the same.
+type HashOptions struct {
+	// IgnorePileOrder treats tableau columns that only differ in position as the same, so moving a king between empty
+	// columns doesn't make a new position.
+	IgnorePileOrder bool
+}
+
+// Positions are hashed Zobrist style: every fact about the position has a random key, and the hash is the XOR of the
+// keys of the facts that hold.  A tableau card's facts are the card, the card it's on and whether it's face up, which
+// says nothing about which column it's in, so the tableau and foundation hashes can be kept up to date by XORing keys
+// in and out as cards are put and got.  Each column's bottom card is only keyed with its column number when pile
+// order matters.
+const (
+	zobristCards     = 53           // the 52 klondike cards, and one key shared by anything else
+	zobristBottom    = zobristCards // what a tableau pile's bottom card is on
+	zobristPositions = 52
+	zobristHeights   = 14
+)
+
+var (
+	zobristTableau    [zobristCards][zobristCards + 1][2]uint64
+	zobristStock      [zobristCards][zobristPositions]uint64
+	zobristWaste      [zobristCards][zobristPositions]uint64
+	zobristFoundation [5][zobristHeights]uint64
+	zobristColumn     uint64
+)
+
+// The keys come from a fixed seed so that hashes are the same from one run to the next.
+func init() {
+	state := uint64(0x676f70617469656e)
+	next := func() uint64 {
+		state += 0x9e3779b97f4a7c15
+		return mix64(state)
+	}
+	for card := range zobristTableau {
+		for under := range zobristTableau[card] {
+			zobristTableau[card][under][0] = next()
+			zobristTableau[card][under][1] = next()
+		}
+		for position := 0; position < zobristPositions; position++ {
+			zobristStock[card][position] = next()
+			zobristWaste[card][position] = next()
+		}
+	}
+	for suitNum := range zobristFoundation {
+		// an empty foundation pile adds nothing, so a new Foundation's hash is zero
+		for height := 1; height < zobristHeights; height++ {
+			zobristFoundation[suitNum][height] = next()
+		}
+	}
+	zobristColumn = next()
+}
+
+// mix64 is the splitmix64 finalizer.
+func mix64(x uint64) uint64 {
+	x = (x ^ x>>30) * 0xbf58476d1ce4e5b9
+	x = (x ^ x>>27) * 0x94d049bb133111eb
+	return x ^ x>>31
+}
+
+func zobristCard(card cards.Card) int {
+	card.Revealed = false
+	if index, found := deckIndex[card]; found {
+		return index
+	}
+	return zobristCards - 1
+}
+
+// tableauKey is the key of the card at cardNum in pile.
+func tableauKey(pile []*cards.Card, cardNum int) uint64 {
+	card, under := pile[cardNum], zobristBottom
+	if cardNum > 0 {
+		under = zobristCard(*pile[cardNum-1])
+	}
+	revealed := 0
+	if card.Revealed {
+		revealed = 1
+	}
+	return zobristTableau[zobristCard(*card)][under][revealed]
+}
+
+func foundationKey(foundationSuit suit.Suit, height int) uint64 {
+	suitNum := len(suit.All)
+	for i, s := range suit.All {
+		if s == foundationSuit {
+			suitNum = i
+			break
+		}
+	}
+	return zobristFoundation[suitNum][height%zobristHeights]
+}
+
+func (t *Tableau) computeHash() uint64 {
+	var hash uint64
+	for _, pile := range t.Piles {
+		for cardNum := range pile {
+			hash ^= tableauKey(pile, cardNum)
+		}
+	}
+	return hash
+}
+
+func (f *Foundation) computeHash() uint64 {
+	var hash uint64
+	for suit, pile := range f.Piles {
+		hash ^= foundationKey(suit, len(pile))
+	}
+	return hash
+}
+
+// Rehash brings the game's hash up to date after its piles have been changed directly instead of through its moves.
+func (k *KlondikeGame) Rehash() {
+	k.Tableau.hash = k.Tableau.computeHash()
+	k.Foundation.hash = k.Foundation.computeHash()
+}
+
+// Hash returns a 64 bit hash of the position: the cards in the stock, waste, foundation and tableau, and which are face
+// up.  The score, options and history aren't part of the position.  Equal positions always hash the same, and the
+// tableau and foundation parts are kept up to date as cards move, so hashing is cheap enough for a solver to call on
+// every position it visits.
+func (k *KlondikeGame) Hash(options HashOptions) uint64 {
+	hash := k.Tableau.hash ^ k.Foundation.hash
+	for cardNum, card := range k.Stock.Cards {
+		// counted from the bottom, which dealing doesn't move
+		hash ^= zobristStock[zobristCard(card)][(len(k.Stock.Cards)-1-cardNum)%zobristPositions]
+	}
+	for cardNum, card := range k.Waste {
+		hash ^= zobristWaste[zobristCard(card)][cardNum%zobristPositions]
+	}
+	if !options.IgnorePileOrder {
+		for pileNum, pile := range k.Tableau.Piles {
+			if len(pile) > 0 {
+				hash ^= mix64(zobristColumn + uint64(pileNum*zobristCards+zobristCard(*pile[0])))
+			}
+		}
+	}
+	return hash
+}
+
+// Equal reports whether the two games are in the same position, comparing the same things Hash does.
+func (k *KlondikeGame) Equal(other *KlondikeGame, options HashOptions) bool {
+	if !sameCards(k.Stock.Cards, other.Stock.Cards) || !sameCards(k.Waste, other.Waste) {
+		return false
+	}
+	if len(k.Foundation.Piles) != len(other.Foundation.Piles) {
+		return false
+	}
+	for suit, pile := range k.Foundation.Piles {
+		otherPile, found := other.Foundation.Piles[suit]
+		if !found || !sameCards(pile, otherPile) {
+			return false
+		}
+	}
+	if len(k.Tableau.Piles) != len(other.Tableau.Piles) {
+		return false
+	}
+	piles, otherPiles := k.Tableau.pileStrings(), other.Tableau.pileStrings()
+	if options.IgnorePileOrder {
+		sort.Strings(piles)
+		sort.Strings(otherPiles)
+	}
+	for pileNum := range piles {
+		if piles[pileNum] != otherPiles[pileNum] {
+			return false
+		}
+	}
+	return true
+}
+
+func sameCards(a, b []cards.Card) bool {
+	if len(a) != len(b) {
+		return false
+	}
+	for i := range a {
+		if a[i] != b[i] {
+			return false
+		}
+	}
+	return true
+}
+
+func (t *Tableau) pileStrings() []string {
+	piles := make([]string, 0, len(t.Piles))
+	for _, pile := range t.Piles {
+		pileString := strings.Builder{}
+		for _, card := range pile {
+			pileString.WriteString(card.String())
+			pileString.WriteString(" ")
+		}
+		piles = append(piles, pileString.String())
+	}
+	return piles
+}
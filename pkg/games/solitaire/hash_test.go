@@ -0,0 +1,72 @@
+package solitaire
+
+import (
+	"math/rand"
+	"testing"
+)
+
+func TestKlondikeGame_Hash_Incremental(t *testing.T) {
+	k := NewSeededKlondikeGame(3)
+	start := k.Hash(HashOptions{})
+	random := rand.New(rand.NewSource(3))
+	for i := 0; i < 300; i++ {
+		moves := k.LegalMoves()
+		if len(moves) == 0 {
+			break
+		}
+		if err := k.Apply(moves[random.Intn(len(moves))]); err != nil {
+			t.Fatal(err)
+		}
+		if i%5 == 4 {
+			k.Undo()
+		}
+		for _, options := range []HashOptions{{}, {IgnorePileOrder: true}} {
+			if k.Hash(options) != k.Clone().Hash(options) {
+				t.Fatalf("Hash after %s should match a full recompute", k.History[len(k.History)-1])
+			}
+		}
+	}
+	for len(k.UndoStack) > 0 {
+		k.Undo()
+	}
+	if k.Hash(HashOptions{}) != start {
+		t.Error("Undoing every move should restore the starting hash")
+	}
+}
+
+func TestKlondikeGame_Hash_PileOrder(t *testing.T) {
+	k := NewSeededKlondikeGame(3)
+	swapped := k.Clone()
+	swapped.Tableau.Piles[2], swapped.Tableau.Piles[5] = swapped.Tableau.Piles[5], swapped.Tableau.Piles[2]
+	swapped.Rehash()
+
+	ordered, unordered := HashOptions{}, HashOptions{IgnorePileOrder: true}
+	if k.Hash(ordered) == swapped.Hash(ordered) || k.Equal(swapped, ordered) {
+		t.Error("Swapped columns should be a different position when pile order matters")
+	}
+	if k.Hash(unordered) != swapped.Hash(unordered) || !k.Equal(swapped, unordered) {
+		t.Error("Swapped columns should be the same position when pile order is ignored")
+	}
+}
+
+func TestKlondikeGame_Hash_Different(t *testing.T) {
+	unordered := HashOptions{IgnorePileOrder: true}
+	// the same cards at the same depths, but the 10♥ is on a different card
+	k := parseTestLayout(t, "0: |K♠ J♣ 10♥\n1: |A♥ J♠")
+	other := parseTestLayout(t, "0: |K♠ J♣\n1: |A♥ J♠ 10♥")
+	if k.Hash(unordered) == other.Hash(unordered) || k.Equal(other, unordered) {
+		t.Error("Columns holding different runs should be different positions")
+	}
+
+	k = NewSeededKlondikeGame(3)
+	dealt := k.Clone()
+	dealt.Deal()
+	if k.Hash(HashOptions{}) == dealt.Hash(HashOptions{}) || k.Equal(dealt, HashOptions{}) {
+		t.Error("Dealing should change the position")
+	}
+	scored := k.Clone()
+	scored.Score = 100
+	if k.Hash(HashOptions{}) != scored.Hash(HashOptions{}) || !k.Equal(scored, HashOptions{}) {
+		t.Error("The score isn't part of the position")
+	}
+}
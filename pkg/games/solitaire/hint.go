@@ -0,0 +1,144 @@
+package solitaire
+
+import (
+	"fmt"
+	"github.com/jamesboehmer/gopatience/pkg/cards"
+	"github.com/jamesboehmer/gopatience/pkg/cards/pip"
+	"sort"
+)
+
+type Hint struct {
+	Move   Move
+	Reason string
+	Rank   int
+}
+
+func (h Hint) String() string {
+	return fmt.Sprintf("%s: %s", h.Move, h.Reason)
+}
+
+// Hints ranks every useful legal move, best first.  Moves that can't change anything, like sliding a king from the
+// bottom of one column into another empty column, are left out.
+func (k *KlondikeGame) Hints() []Hint {
+	var hints []Hint
+	for _, move := range k.LegalMoves() {
+		rank, reason := k.rankMove(move)
+		if rank > 0 {
+			hints = append(hints, Hint{Move: move, Reason: reason, Rank: rank})
+		}
+	}
+	sort.SliceStable(hints, func(i, j int) bool { return hints[i].Rank > hints[j].Rank })
+	return hints
+}
+
+// Hint returns the best move for the current position.  Calling it again without changing the position cycles through
+// the rest of the ranked hints.
+func (k *KlondikeGame) Hint() (Hint, error) {
+	hints := k.Hints()
+	if len(hints) == 0 {
+		return Hint{}, ErrNoMoves
+	}
+	if !sameHints(hints, k.hints) {
+		k.hints = hints
+		k.hintNum = -1
+	}
+	k.hintNum = (k.hintNum + 1) % len(k.hints)
+	return k.hints[k.hintNum], nil
+}
+
+func sameHints(a []Hint, b []Hint) bool {
+	if len(a) != len(b) {
+		return false
+	}
+	for i := range a {
+		if a[i] != b[i] {
+			return false
+		}
+	}
+	return true
+}
+
+func (k *KlondikeGame) rankMove(move Move) (int, string) {
+	switch move.Type {
+	case MoveTableauFoundation:
+		card := k.Tableau.Piles[move.FromPile][move.CardNum]
+		if k.revealsCard(move) {
+			return 90, fmt.Sprintf("%s goes home and reveals a face-down card", card)
+		}
+		if k.Foundation.isSafe(*card) {
+			return 70, fmt.Sprintf("%s goes home", card)
+		}
+		return 20, fmt.Sprintf("%s goes home, but it may still be needed for building", card)
+	case MoveWasteFoundation:
+		card := k.Waste[len(k.Waste)-1]
+		if k.Foundation.isSafe(card) {
+			return 60, fmt.Sprintf("%s goes home from the waste", &card)
+		}
+		return 20, fmt.Sprintf("%s goes home from the waste, but it may still be needed for building", &card)
+	case MoveTableauTableau:
+		card := k.Tableau.Piles[move.FromPile][move.CardNum]
+		if k.revealsCard(move) {
+			// prefer uncovering the longest hidden piles
+			return 80 + move.CardNum, fmt.Sprintf("moving %s reveals a face-down card", card)
+		}
+		if move.CardNum == 0 {
+			if card.Pip == pip.King {
+				return 0, ""
+			}
+			if k.hasHomelessKing() {
+				return 50, fmt.Sprintf("moving %s empties a column for a king", card)
+			}
+			return 10, fmt.Sprintf("moving %s empties a column", card)
+		}
+		if below := k.Tableau.Piles[move.FromPile][move.CardNum-1]; k.Foundation.fits(*below) {
+			return 45, fmt.Sprintf("moving %s lets %s go home", card, below)
+		}
+		return 0, ""
+	case MoveWasteTableau:
+		card := k.Waste[len(k.Waste)-1]
+		if card.Pip == pip.King {
+			return 35, fmt.Sprintf("%s from the waste fills an empty column", &card)
+		}
+		return 40, fmt.Sprintf("%s from the waste builds on the tableau", &card)
+	case MoveFoundationTableau:
+		return 5, fmt.Sprintf("%s comes back from the foundation to build on", move.Suit)
+	case MoveDeal:
+		return 15, "deal a new card from the stock"
+	}
+	return 0, ""
+}
+
+// movingCard returns the card a move picks up, or the first of them for a tableau run.
+func (k *KlondikeGame) movingCard(move Move) *cards.Card {
+	switch move.Type {
+	case MoveWasteFoundation, MoveWasteTableau:
+		return &k.Waste[len(k.Waste)-1]
+	case MoveTableauFoundation, MoveTableauTableau:
+		return k.Tableau.Piles[move.FromPile][move.CardNum]
+	case MoveFoundationTableau:
+		pile := k.Foundation.Piles[move.Suit]
+		return &pile[len(pile)-1]
+	}
+	return nil
+}
+
+// revealsCard reports whether taking the move's cards off the tableau turns up a face-down card.
+func (k *KlondikeGame) revealsCard(move Move) bool {
+	return move.CardNum > 0 && !k.Tableau.Piles[move.FromPile][move.CardNum-1].Revealed
+}
+
+// hasHomelessKing reports whether a king is waiting for an empty column, either on top of the waste or on top of
+// face-down cards in the tableau.
+func (k *KlondikeGame) hasHomelessKing() bool {
+	if len(k.Waste) > 0 && k.Waste[len(k.Waste)-1].Pip == pip.King {
+		return true
+	}
+	for _, pile := range k.Tableau.Piles {
+		for cardNum, card := range pile {
+			if card.Revealed && card.Pip == pip.King && cardNum > 0 && !pile[cardNum-1].Revealed {
+				return true
+			}
+		}
+	}
+	return false
+}
@@ -0,0 +1,81 @@
+package solitaire
+
+import (
+	"github.com/jamesboehmer/gopatience/pkg/cards"
+	"strings"
+	"testing"
+)
+
+// hintLayout has an ace to send home and a nine to build, each over a face-down card, and four empty columns.
+const hintLayout = `
+stock: |7♥
+0: |5♣ 10♦
+1: |3♠ 9♠
+2: |4♥ A♣
+3:
+4:
+5:
+6:
+`
+
+func TestKlondikeGame_Hints(t *testing.T) {
+	k := parseTestLayout(t, hintLayout)
+	expected := []Move{
+		{Type: MoveTableauFoundation, FromPile: 2, CardNum: 1},
+		{Type: MoveTableauTableau, FromPile: 1, CardNum: 1, ToPile: 0},
+		{Type: MoveDeal},
+	}
+	hints := k.Hints()
+	if len(hints) != len(expected) {
+		t.Fatalf("Expected %d hints, got %d: %v", len(expected), len(hints), hints)
+	}
+	for i, hint := range hints {
+		if hint.Move != expected[i] {
+			t.Errorf("Expected hint %d to be %s, got %s", i, expected[i], hint.Move)
+		}
+	}
+	if hints[0].String() != "tableau 2 to foundation: A♣ goes home and reveals a face-down card" {
+		t.Errorf("Unexpected hint text %q", hints[0])
+	}
+}
+
+func TestKlondikeGame_HintsSkipPointlessMoves(t *testing.T) {
+	k := parseTestLayout(t, strings.Replace(hintLayout, "3:", "3: K♠", 1))
+	for _, hint := range k.Hints() {
+		if hint.Move.FromPile == 3 {
+			t.Error("Moving a king between empty columns should not be hinted")
+		}
+	}
+}
+
+func TestKlondikeGame_HintCycles(t *testing.T) {
+	k := parseTestLayout(t, hintLayout)
+	hints := k.Hints()
+	for i := 0; i < 2*len(hints); i++ {
+		hint, err := k.Hint()
+		if err != nil {
+			t.Fatal("Hint should not return an error when there are moves")
+		}
+		if hint != hints[i%len(hints)] {
+			t.Errorf("Hint %d should have been %s, got %s", i, hints[i%len(hints)], hint)
+		}
+	}
+	// a different position starts over from the best hint
+	k.Hint()
+	k.Apply(hints[1].Move)
+	hint, _ := k.Hint()
+	if hint != k.Hints()[0] {
+		t.Error("Hint should start over after the position changes")
+	}
+}
+
+func TestKlondikeGame_HintNoMoves(t *testing.T) {
+	k := NewKlondikeGame()
+	k.Stock.Cards = []cards.Card{}
+	for pileNum := range k.Tableau.Piles {
+		k.Tableau.Piles[pileNum] = []*cards.Card{}
+	}
+	if _, err := k.Hint(); err == nil {
+		t.Error("Hint should return an error when there are no moves")
+	}
+}
@@ -1,21 +1,31 @@
 package solitaire
 
 import (
-	"errors"
+	"fmt"
 	"github.com/jamesboehmer/gopatience/pkg/cards"
 	"github.com/jamesboehmer/gopatience/pkg/cards/pip"
 	"github.com/jamesboehmer/gopatience/pkg/cards/suit"
 	"github.com/jamesboehmer/gopatience/pkg/games/util"
+	"strings"
+	"time"
 )
 
 type KlondikeGame struct {
 	util.Undoable
-	Score      int
-	Errors     []error
-	Stock      cards.Deck
-	Waste      []cards.Card
-	Foundation Foundation
-	Tableau    Tableau
+	Score        int
+	Seed         int64
+	Errors       []error
+	Stock        cards.Deck
+	Waste        []cards.Card
+	Foundation   Foundation
+	Tableau      Tableau
+	AutoPlay     bool
+	Destinations DestinationPolicy
+	History      []Move
+	Unranked     bool
+	hints        []Hint
+	hintNum      int
+	order        []cards.Card
 }
 
 const (
@@ -24,32 +34,33 @@ const (
 	PointsTableauFoundation int = 15
 )
 
+var klondikeSuits = []suit.Suit{suit.Hearts, suit.Diamonds, suit.Clubs, suit.Spades}
+
 func (k *KlondikeGame) Deal() error {
+	return k.Apply(Move{Type: MoveDeal})
+}
+
+func (k *KlondikeGame) deal() {
 	replenished := false
 	if k.Stock.Remaining() == 0 {
-		if len(k.Waste) > 0 {
-			for _, card := range k.Waste {
-				k.Stock.Cards = append(k.Stock.Cards, *card.Conceal())
-			}
-			k.Waste = []cards.Card{}
-			replenished = true
-		} else {
-			return errors.New("no cards remaining")
+		for _, card := range k.Waste {
+			k.Stock.Cards = append(k.Stock.Cards, *card.Conceal())
 		}
+		k.Waste = []cards.Card{}
+		replenished = true
 	}
 	k.Waste = append(k.Waste, *k.Stock.Deal().Reveal())
 	k.UndoStack = append(k.UndoStack, util.UndoAction{
 		Function: k.undoDeal,
 		Args:     []interface{}{replenished},
 	})
-	return nil
 }
 
 func (k *KlondikeGame) undoDeal(args ...interface{}) error {
 	replenished := args[0].(bool)
 	card := k.Waste[len(k.Waste)-1]
 	k.Waste = k.Waste[:len(k.Waste)-1]
-	k.Stock.Cards = append([]cards.Card{*card.Reveal()}, k.Stock.Cards...)
+	k.Stock.Cards = append([]cards.Card{*card.Conceal()}, k.Stock.Cards...)
 	if replenished {
 		for card := range k.Stock.DealAll() {
 			k.Waste = append(k.Waste, *card.Reveal())
@@ -58,33 +69,44 @@ func (k *KlondikeGame) undoDeal(args ...interface{}) error {
 	return nil
 }
 
+// Undo takes back the last move and drops it from the history.
+func (k *KlondikeGame) Undo() error {
+	if len(k.UndoStack) == 0 {
+		return nil
+	}
+	valid := debugValidation && k.isValid()
+	// a game can have undo steps from before its history was kept, so there may not be a move to drop
+	var move Move
+	if len(k.History) > 0 {
+		move = k.History[len(k.History)-1]
+		k.History = k.History[:len(k.History)-1]
+	}
+	err := k.Undoable.Undo()
+	if valid {
+		k.mustStayValid("undoing " + move.String())
+	}
+	return err
+}
+
 func (k *KlondikeGame) adjustScore(points int) {
 	k.Score += points
 }
 
 func (k *KlondikeGame) SelectFoundation(suit suit.Suit, tableauDestinations ...int) error {
-	card, err := k.Foundation.Get(suit)
-	if err != nil {
-		return err
+	if err := k.Foundation.checkGet(suit); err != nil {
+		return located(err, "%s foundation", suit)
 	}
 	if tableauDestinations == nil || len(tableauDestinations) == 0 {
 		for i := 0; i < len(k.Tableau.Piles); i++ {
 			tableauDestinations = append(tableauDestinations, i)
 		}
 	}
+	var moves []Move
 	for _, pileNum := range tableauDestinations {
-		err := k.Tableau.Put([]*cards.Card{card}, pileNum)
-		if err == nil {
-			k.adjustScore(-PointsTableauFoundation)
-			k.UndoStack = append(k.UndoStack, util.UndoAction{
-				Function: k.undoSelectFoundation,
-				Args:     nil,
-			})
-			return nil
-		}
+		moves = append(moves, Move{Type: MoveFoundationTableau, Suit: suit, ToPile: pileNum})
 	}
-	k.Foundation.Undo()
-	return errors.New("no tableau fit")
+	pile := k.Foundation.Piles[suit]
+	return k.tryMoves(pile[len(pile)-1], moves)
 }
 
 func (k *KlondikeGame) undoSelectFoundation(...interface{}) error {
@@ -96,22 +118,13 @@ func (k *KlondikeGame) undoSelectFoundation(...interface{}) error {
 
 func (k *KlondikeGame) SelectWaste(tableauDestinations ...int) error {
 	if len(k.Waste) == 0 {
-		return errors.New("no cards left in the waste pile")
+		return fmt.Errorf("waste: %w", ErrEmptyPile)
 	}
-	topCard := k.Waste[len(k.Waste)-1]
-	k.Waste = k.Waste[:len(k.Waste)-1]
 
 	// try moving from the waste to the foundation if there was no tableau pile specified
+	var moves []Move
 	if tableauDestinations == nil {
-		err := k.Foundation.Put(topCard)
-		if err == nil {
-			k.adjustScore(PointsWasteFoundation)
-			k.UndoStack = append(k.UndoStack, util.UndoAction{
-				Function: k.undoSelectWaste,
-				Args:     []interface{}{true, topCard},
-			})
-			return nil
-		}
+		moves = append(moves, Move{Type: MoveWasteFoundation})
 	}
 	// if there was no fit, then try the tableau
 	if tableauDestinations == nil || len(tableauDestinations) == 0 {
@@ -121,19 +134,9 @@ func (k *KlondikeGame) SelectWaste(tableauDestinations ...int) error {
 	}
 
 	for _, pileNum := range tableauDestinations {
-		err := k.Tableau.Put([]*cards.Card{&topCard}, pileNum)
-		if err == nil {
-			k.adjustScore(PointsWasteTableau)
-			k.UndoStack = append(k.UndoStack, util.UndoAction{
-				Function: k.undoSelectWaste,
-				Args:     []interface{}{false, topCard},
-			})
-			return nil
-		}
+		moves = append(moves, Move{Type: MoveWasteTableau, ToPile: pileNum})
 	}
-	// if there was no fit, put the card back on the waste pile
-	k.Waste = append(k.Waste, topCard)
-	return errors.New("no tableau fit")
+	return k.tryMoves(k.Waste[len(k.Waste)-1], moves)
 }
 
 func (k *KlondikeGame) undoSelectWaste(args ...interface{}) error {
@@ -150,71 +153,76 @@ func (k *KlondikeGame) undoSelectWaste(args ...interface{}) error {
 }
 
 func (k *KlondikeGame) seekTableauToFoundation() error {
+	_, err := k.seekFoundation(false, false)
+	return err
+}
+
+// seekFoundation moves the first tableau card, or the top waste card if fromWaste is set, that fits in the foundation.
+// With safeOnly, cards that could still be needed for building on the tableau are left where they are.
+func (k *KlondikeGame) seekFoundation(fromWaste bool, safeOnly bool) (Move, error) {
+	var candidates []Move
+	if fromWaste {
+		candidates = append(candidates, Move{Type: MoveWasteFoundation})
+	}
 	// Seek a tableau pile whose top card fits in the foundation
-	for pileNum, _ := range k.Tableau.Piles {
-		cards, err := k.Tableau.Get(pileNum, len(k.Tableau.Piles[pileNum])-1)
-		if err == nil { // we got a card from the tableau, now let's find a foundation fit
-			fErr := k.Foundation.Put(*cards[0])
-			if fErr == nil { // yay it was a tableau fit!  push the undo stack and return
-				k.adjustScore(PointsTableauFoundation)
-				k.UndoStack = append(k.UndoStack, util.UndoAction{
-					Function: k.undoSeekTableauToFoundation,
-					Args:     nil,
-				})
-				return nil
-			}
-			// it wasn't a fit in the tableau, so put the card back and move on to the next tableau pile
-			k.Tableau.Undo()
+	for pileNum, pile := range k.Tableau.Piles {
+		candidates = append(candidates, Move{Type: MoveTableauFoundation, FromPile: pileNum, CardNum: len(pile) - 1})
+	}
+	for _, move := range candidates {
+		if !k.IsLegal(move) {
+			continue
+		}
+		if safeOnly && !k.Foundation.isSafe(*k.movingCard(move)) {
+			continue
 		}
+		return move, k.apply(move)
 	}
-	return errors.New("no tableau cards fit the foundation")
+	return Move{}, fmt.Errorf("foundation: %w", ErrNoFit)
 }
 
-func (k *KlondikeGame) undoSeekTableauToFoundation(...interface{}) error {
-	k.adjustScore(-PointsTableauFoundation)
-	k.Foundation.Undo()
-	k.Tableau.Undo()
-	return nil
+// PlaySafe moves every waste and tableau card that can never be needed on the tableau again to the foundation, one
+// undoable move per card, and returns the moves it made.
+func (k *KlondikeGame) PlaySafe() []Move {
+	var moves []Move
+	for {
+		move, err := k.seekFoundation(true, true)
+		if err != nil {
+			return moves
+		}
+		moves = append(moves, move)
+	}
 }
 
 func (k *KlondikeGame) SelectTableau(pileNum int, cardDestination ...int) error {
 	if pileNum < 0 || pileNum > len(k.Tableau.Piles)-1 {
-		return errors.New("invalid pileNum")
+		return fmt.Errorf("tableau %d: %w", pileNum, ErrInvalidPile)
 	}
 	if len(k.Tableau.Piles[pileNum]) == 0 {
-		return errors.New("empty pile")
+		return fmt.Errorf("tableau %d: %w", pileNum, ErrEmptyPile)
 	}
 	cardNum := -1
 	if len(cardDestination) > 0 {
 		cardNum = cardDestination[0]
 	}
 	if len(cardDestination) > 1 {
-		if cardDestination[1] == pileNum || cardDestination[1] < 0 { //|| cardDestination[1] > len(k.Tableau.Piles)-1{
-			return errors.New("invalid destination")
+		if cardDestination[1] == pileNum || cardDestination[1] < 0 {
+			return fmt.Errorf("tableau %d: %w", cardDestination[1], ErrInvalidPile)
 		}
 	}
 	if cardNum < 0 {
 		// it's valid to ask for a negative index, just convert it to the positive offset
 		cardNum = len(k.Tableau.Piles[pileNum]) + cardNum
 		if cardNum < 0 {
-			return errors.New("invalid cardNum")
+			return fmt.Errorf("tableau %d card %d: %w", pileNum, cardDestination[0], ErrInvalidCard)
 		}
 	}
-	cards, err := k.Tableau.Get(pileNum, cardNum) //needs to be undone if we can't find a fit
-	if err != nil {
-		return err
+	if err := k.Tableau.checkGet(pileNum, cardNum); err != nil {
+		return located(err, "tableau %d card %d", pileNum, cardNum)
 	}
 	// If there's only 1 card selected from the tableau, and no destination specified, try to fit it in the foundation
-	if len(cards) == 1 && len(cardDestination) < 2 {
-		err = k.Foundation.Put(*cards[0])
-		if err == nil {
-			k.adjustScore(PointsTableauFoundation)
-			k.UndoStack = append(k.UndoStack, util.UndoAction{
-				Function: k.undoSelectTableau,
-				Args:     []interface{}{true},
-			})
-			return nil
-		}
+	var moves []Move
+	if cardNum == len(k.Tableau.Piles[pileNum])-1 && len(cardDestination) < 2 {
+		moves = append(moves, Move{Type: MoveTableauFoundation, FromPile: pileNum, CardNum: cardNum})
 		// don't quit here just because we didn't find a foundation fit.
 	}
 
@@ -228,23 +236,17 @@ func (k *KlondikeGame) SelectTableau(pileNum int, cardDestination ...int) error
 		}
 	}
 
-	for _, pileNum := range tableauDestinations {
-		err := k.Tableau.Put(cards, pileNum)
-		if err == nil {
-			k.adjustScore(PointsWasteTableau)
-			k.UndoStack = append(k.UndoStack, util.UndoAction{
-				Function: k.undoSelectTableau,
-				Args:     []interface{}{false},
+	for _, destination := range tableauDestinations {
+		if destination != pileNum {
+			moves = append(moves, Move{
+				Type: MoveTableauTableau, FromPile: pileNum, CardNum: cardNum, ToPile: destination,
 			})
-			return nil
 		}
 	}
-	// We couldn't find a card in the tableau that fit in the foundation
-	// OR The chosen tableau card didn't fit in the foundation
+	// The chosen tableau card didn't fit in the foundation
 	// OR The chosen tableau card didn't fit anywhere in the tableau
 	// OR the chosen tableau card didn't fit in the chosen tableau pile
-	k.Tableau.Undo()
-	return errors.New("no fit for chosen card(s)")
+	return k.tryMoves(*k.Tableau.Piles[pileNum][cardNum], moves)
 }
 
 func (k *KlondikeGame) undoSelectTableau(args ...interface{}) error {
@@ -253,9 +255,10 @@ func (k *KlondikeGame) undoSelectTableau(args ...interface{}) error {
 		k.adjustScore(-PointsTableauFoundation)
 		k.Foundation.Undo() //undo put
 	} else {
+		k.adjustScore(-PointsWasteTableau)
 		k.Tableau.Undo() //undo put
-		k.Tableau.Undo() //undo get
 	}
+	k.Tableau.Undo() //undo get
 	return nil
 }
 
@@ -277,7 +280,7 @@ func (k *KlondikeGame) IsSolved() bool {
 
 func (k *KlondikeGame) Solve() error {
 	if !k.IsSolvable() {
-		return errors.New("game is not solvable yet")
+		return ErrNotSolvable
 	}
 
 	if !k.IsSolved() {
@@ -286,14 +289,160 @@ func (k *KlondikeGame) Solve() error {
 	return nil
 }
 
+// Finish plays every remaining card to the foundation once the game is solvable, lowest ranks first, as one undoable
+// move per card.  If report isn't nil it's called after each move, so callers can show the game being played out.
+func (k *KlondikeGame) Finish(report func(Move)) ([]Move, error) {
+	if !k.IsSolvable() {
+		return nil, ErrNotSolvable
+	}
+	var moves []Move
+	for !k.IsSolved() {
+		var next Move
+		lowest := 0
+		for pileNum, pile := range k.Tableau.Piles {
+			move := Move{Type: MoveTableauFoundation, FromPile: pileNum, CardNum: len(pile) - 1}
+			if !k.IsLegal(move) {
+				continue
+			}
+			if value := PipValue[pile[len(pile)-1].Pip]; lowest == 0 || value < lowest {
+				next, lowest = move, value
+			}
+		}
+		if lowest == 0 {
+			return moves, fmt.Errorf("foundation: %w", ErrNoFit)
+		}
+		k.apply(next)
+		moves = append(moves, next)
+		if report != nil {
+			report(next)
+		}
+	}
+	return moves, nil
+}
+
 func NewKlondikeGame() *KlondikeGame {
+	return NewSeededKlondikeGame(time.Now().UnixNano())
+}
+
+// NewSeededKlondikeGame deals a new game from a deck shuffled with seed, so the same seed always deals the same game.
+func NewSeededKlondikeGame(seed int64) *KlondikeGame {
+	game := dealKlondikeGame(*cards.NewDeck(1, 0).ShuffleSeed(seed))
+	game.Seed = seed
+	return game
+}
+
+// NewKlondikeGameFromDeck deals a new game from the deck's cards in order, starting with deck.Cards[0], so deals from
+// elsewhere can be played again.  The deck must be exactly one of each of the 52 cards; if it isn't, the error is a
+// *DeckError saying which cards are missing or repeated.  The deck itself isn't changed.
+func NewKlondikeGameFromDeck(deck *cards.Deck) (*KlondikeGame, error) {
+	if err := checkKlondikeDeck(deck.Cards); err != nil {
+		return nil, err
+	}
+	ordered := *deck
+	ordered.Cards = make([]cards.Card, 0, len(deck.Cards))
+	for _, card := range deck.Cards {
+		ordered.Cards = append(ordered.Cards, *card.Conceal())
+	}
+	return dealKlondikeGame(ordered), nil
+}
+
+// A DeckError lists what's wrong with a deck that isn't exactly one of each klondike card.
+type DeckError struct {
+	Missing  []cards.Card
+	Repeated []cards.Card
+	Extra    []cards.Card
+}
+
+func (e *DeckError) Error() string {
+	var problems []string
+	describe := func(what string, list []cards.Card) {
+		if len(list) == 0 {
+			return
+		}
+		var names []string
+		for _, card := range list {
+			card.Revealed = true
+			names = append(names, card.String())
+		}
+		problems = append(problems, what+" "+strings.Join(names, " "))
+	}
+	describe("missing", e.Missing)
+	describe("repeated", e.Repeated)
+	describe("extra", e.Extra)
+	return "invalid deck: " + strings.Join(problems, "; ")
+}
+
+func checkKlondikeDeck(deck []cards.Card) error {
+	counts := make(map[cards.Card]int, len(deck))
+	deckError := new(DeckError)
+	for _, card := range deck {
+		card.Revealed = false
+		if _, found := deckIndex[card]; !found {
+			deckError.Extra = append(deckError.Extra, card)
+			continue
+		}
+		counts[card]++
+	}
+	for _, suit := range suit.All {
+		for _, pip := range pip.All {
+			card := cards.Card{Pip: pip, Suit: suit}
+			switch {
+			case counts[card] == 0:
+				deckError.Missing = append(deckError.Missing, card)
+			case counts[card] > 1:
+				deckError.Repeated = append(deckError.Repeated, card)
+			}
+		}
+	}
+	if len(deckError.Missing)+len(deckError.Repeated)+len(deckError.Extra) > 0 {
+		return deckError
+	}
+	return nil
+}
+
+// dealKlondikeGame deals a new game from the deck's cards in order, and remembers the order for DealCode.
+func dealKlondikeGame(deck cards.Deck) *KlondikeGame {
 	game := new(KlondikeGame)
-	game.Stock = *cards.NewDeck(1, 0).Shuffle()
-	game.Foundation = *NewFoundation([]suit.Suit{suit.Hearts, suit.Diamonds, suit.Clubs, suit.Spades})
+	game.order = append([]cards.Card{}, deck.Cards...)
+	game.Stock = deck
+	game.Stock.Cards = append([]cards.Card{}, deck.Cards...)
+	game.Foundation = *NewFoundation(klondikeSuits)
 	game.Tableau = *NewTableau(7, &game.Stock)
 	return game
 }
 
+// Clone deep-copies the game, so the copy can be played, or its cards changed, without touching k.  The copy has the
+// same cards, score, options and history, but nothing to undo, since undo actions only ever act on the game that
+// made them.
+func (k *KlondikeGame) Clone() *KlondikeGame {
+	game := new(KlondikeGame)
+	game.Score = k.Score
+	game.Seed = k.Seed
+	game.AutoPlay = k.AutoPlay
+	game.Destinations = k.Destinations
+	game.Unranked = k.Unranked
+	game.order = k.order
+	game.Errors = append([]error{}, k.Errors...)
+	game.History = append([]Move{}, k.History...)
+	game.Stock = k.Stock
+	game.Stock.Cards = append([]cards.Card{}, k.Stock.Cards...)
+	game.Waste = append([]cards.Card{}, k.Waste...)
+	game.Foundation.Piles = make(map[suit.Suit][]cards.Card, len(k.Foundation.Piles))
+	for suit, pile := range k.Foundation.Piles {
+		game.Foundation.Piles[suit] = append(make([]cards.Card, 0, 13), pile...)
+	}
+	game.Tableau.Piles = make([][]*cards.Card, len(k.Tableau.Piles))
+	for pileNum, pile := range k.Tableau.Piles {
+		game.Tableau.Piles[pileNum] = make([]*cards.Card, 0, len(pile)+13)
+		for _, card := range pile {
+			card := *card
+			game.Tableau.Piles[pileNum] = append(game.Tableau.Piles[pileNum], &card)
+		}
+	}
+	game.Rehash()
+	return game
+}
+
 var PipValue = map[pip.Pip]int{
 	pip.Ace:   1,
 	pip.Two:   2,
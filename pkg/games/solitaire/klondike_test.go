@@ -104,6 +104,18 @@ func TestKlondikeGame_undoDeal(t *testing.T) {
 	}
 }
 
+func TestKlondikeGame_UndoWithoutHistory(t *testing.T) {
+	k := NewSeededKlondikeGame(3)
+	k.Deal()
+	k.Deal()
+	k.History = k.History[:1]
+	k.Undo()
+	k.Undo()
+	if len(k.Waste) != 0 || len(k.UndoStack) != 0 || len(k.History) != 0 {
+		t.Error("Undo should take back every move even when the history is shorter than the undo stack")
+	}
+}
+
 func TestKlondikeGame_adjustScore(t *testing.T) {
 	k := NewKlondikeGame()
 	k.adjustScore(100)
@@ -521,3 +533,174 @@ func TestKlondikeGame_seekTableauToFoundation(t *testing.T) {
 	}
 
 }
+
+func TestKlondikeGame_PlaySafe(t *testing.T) {
+	k := NewKlondikeGame()
+	for pileNum, cardString := range []string{"A♠", "2♠", "3♥", "6♣", "3♦", "9♥", "2♦"} {
+		card, _ := cards.ParseCard(cardString)
+		k.Tableau.Piles[pileNum] = []*cards.Card{card}
+	}
+	card, _ := cards.ParseCard("A♦")
+	k.Waste = []cards.Card{*card}
+	moves := k.PlaySafe()
+	if len(moves) != 4 {
+		t.Fatalf("A♦, A♠, 2♠ and 2♦ should have gone home, not %v", moves)
+	}
+	if len(k.UndoStack) != 4 {
+		t.Error("Each card should be a separate undoable move")
+	}
+	if len(k.Foundation.Piles[suit.Hearts]) != 0 || len(k.Tableau.Piles[2]) != 1 {
+		t.Error("3♥ isn't safe until both black twos are home")
+	}
+	if len(k.PlaySafe()) != 0 {
+		t.Error("There should be nothing left to play")
+	}
+}
+
+func TestKlondikeGame_AutoPlay(t *testing.T) {
+	k := NewKlondikeGame()
+	for pileNum, cardString := range []string{"A♠", "2♠", "3♥", "6♣", "3♦", "9♥", "2♦"} {
+		card, _ := cards.ParseCard(cardString)
+		k.Tableau.Piles[pileNum] = []*cards.Card{card}
+	}
+	card, _ := cards.ParseCard("|K♦")
+	k.Stock.Cards = []cards.Card{*card}
+	k.Deal()
+	if len(k.UndoStack) != 1 {
+		t.Error("Nothing should be played automatically when AutoPlay is off")
+	}
+	k.Undo()
+
+	k.AutoPlay = true
+	k.Deal()
+	if len(k.UndoStack) != 3 || len(k.Foundation.Piles[suit.Spades]) != 2 {
+		t.Error("A♠ and 2♠ should have followed the deal home")
+	}
+	k.Undo()
+	if len(k.Foundation.Piles[suit.Spades]) != 1 || len(k.Tableau.Piles[1]) != 1 || len(k.Waste) != 1 {
+		t.Error("Undo should only take back the last automatic move")
+	}
+}
+
+func TestKlondikeGame_Finish(t *testing.T) {
+	k := NewKlondikeGame()
+	if _, err := k.Finish(nil); err == nil {
+		t.Error("Should return an error when the game is not solvable")
+	}
+	// stockAcesLayout with the aces dealt onto the runs
+	k = parseTestLayout(t, `
+0: K♥ Q♠ J♦ 10♣ 9♥ 8♠ 7♦ 6♣ 5♥ 4♠ 3♦ 2♣ A♥
+1: K♣ Q♥ J♠ 10♦ 9♣ 8♥ 7♠ 6♦ 5♣ 4♥ 3♠ 2♦ A♣
+2: K♦ Q♣ J♥ 10♠ 9♦ 8♣ 7♥ 6♠ 5♦ 4♣ 3♥ 2♠ A♦
+3: K♠ Q♦ J♣ 10♥ 9♠ 8♦ 7♣ 6♥ 5♠ 4♦ 3♣ 2♥ A♠
+`)
+
+	var reported []Move
+	moves, err := k.Finish(func(move Move) { reported = append(reported, move) })
+	if err != nil {
+		t.Fatalf("Finish should not have returned an error: %s", err)
+	}
+	if !k.IsSolved() {
+		t.Error("The game should be solved")
+	}
+	if len(moves) != 52 || len(reported) != 52 || len(k.UndoStack) != 52 {
+		t.Error("Every card should have been reported as its own undoable move")
+	}
+	// the aces are on top of the runs, so the lowest ranks must come off them first
+	for i, move := range moves[:4] {
+		if move.CardNum != 12 {
+			t.Errorf("Move %d should have played an ace off the top of a run, not %s", i, move)
+		}
+	}
+	k.Undo()
+	if k.IsSolved() {
+		t.Error("Undo should take back only the last card")
+	}
+}
+
+func TestKlondikeGame_FinishStuck(t *testing.T) {
+	k := NewKlondikeGame()
+	k.Stock.Cards = []cards.Card{}
+	for pileNum := range k.Tableau.Piles {
+		k.Tableau.Piles[pileNum] = []*cards.Card{}
+	}
+	k.Tableau.Piles[0] = []*cards.Card{{Pip: pip.Two, Suit: suit.Hearts, Revealed: true}}
+	if moves, err := k.Finish(nil); err == nil || len(moves) != 0 {
+		t.Error("Should return an error when no card fits the foundation")
+	}
+}
+
+func TestNewKlondikeGameFromDeck(t *testing.T) {
+	deck := cards.NewDeck(1, 0).ShuffleSeed(7)
+	for i := range deck.Cards {
+		deck.Cards[i].Reveal()
+	}
+	k, err := NewKlondikeGameFromDeck(deck)
+	if err != nil {
+		t.Fatalf("A full deck should deal, got %s", err)
+	}
+	if !k.Equal(NewSeededKlondikeGame(7), HashOptions{}) {
+		t.Error("Dealing from a deck should deal its cards in order")
+	}
+	if err := k.Validate(); err != nil {
+		t.Errorf("Dealing from face-up cards should still turn the right ones face down, got %s", err)
+	}
+	if !deck.Cards[0].Revealed || len(deck.Cards) != 52 {
+		t.Error("Dealing should not change the deck")
+	}
+	if code, _ := k.DealCode(); code == "" {
+		t.Error("Games dealt from a deck should have a deal code")
+	}
+}
+
+func TestNewKlondikeGameFromDeck_Invalid(t *testing.T) {
+	deck := cards.NewDeck(1, 1)
+	deck.Cards[1] = deck.Cards[0]
+	deck.Cards = deck.Cards[:50]
+	_, err := NewKlondikeGameFromDeck(deck)
+	deckError, ok := err.(*DeckError)
+	if !ok {
+		t.Fatalf("Expected a DeckError, got %v", err)
+	}
+	if len(deckError.Missing) != 3 || len(deckError.Repeated) != 1 || len(deckError.Extra) != 0 {
+		t.Errorf("Expected 3 missing cards and 1 repeated card, got %+v", deckError)
+	}
+	if err.Error() != "invalid deck: missing 2♠ Q♣ K♣; repeated A♠" {
+		t.Errorf("Unexpected error %q", err)
+	}
+	deck, _ = cards.ParseDeck("*")
+	if _, err := NewKlondikeGameFromDeck(deck); err == nil || len(err.(*DeckError).Extra) != 1 {
+		t.Error("Jokers should be reported as extra cards")
+	}
+}
+
+func TestKlondikeGame_Clone(t *testing.T) {
+	k := NewSeededKlondikeGame(7)
+	k.AutoPlay = true
+	k.Deal()
+	layout := k.Layout()
+
+	clone := k.Clone()
+	if clone.Layout() != layout || clone.Seed != k.Seed || !clone.AutoPlay || len(clone.History) != 1 {
+		t.Fatal("Clone should copy the cards, score, options and history")
+	}
+	if len(clone.UndoStack) != 0 {
+		t.Error("Clone should have nothing to undo")
+	}
+	clone.Tableau.Piles[6][0].Reveal()
+	clone.Foundation.Piles[suit.Hearts] = append(clone.Foundation.Piles[suit.Hearts], cards.Card{Pip: pip.Ace})
+	clone.Waste[0].Conceal()
+	for i := 0; i < 30; i++ {
+		clone.Deal()
+	}
+	for _, move := range clone.LegalMoves() {
+		clone.Apply(move)
+	}
+	if k.Layout() != layout || len(k.History) != 1 {
+		t.Error("Playing a clone should not change the original game")
+	}
+	k.Undo()
+	if k.Stock.Remaining() != 24 {
+		t.Error("The original game should still undo its own moves")
+	}
+}
@@ -0,0 +1,149 @@
+package solitaire
+
+import (
+	"errors"
+	"fmt"
+	"github.com/jamesboehmer/gopatience/pkg/cards"
+	"github.com/jamesboehmer/gopatience/pkg/cards/pip"
+	"strconv"
+	"strings"
+)
+
+// Layout writes the game out one line per pile, in the format ParseKlondikeLayout reads:
+//
+//	score: 15
+//	stock: |3♦ |K♣ |Q♠
+//	waste: 2♣ 7♥
+//	foundation: A♥ 2♠
+//	0: |K♠ 9♣
+//	1: 10♥
+//	...
+//
+// Cards are written the way cards.ParseCard reads them, with a "|" prefix for face-down cards.  The stock is listed
+// from the next card to be dealt, the waste and tableau piles from the bottom up, and the foundation by the top card
+// of each pile that has one.
+func (k *KlondikeGame) Layout() string {
+	layout := strings.Builder{}
+	writeLine := func(label string, tokens []string) {
+		layout.WriteString(strings.TrimSpace(label + ": " + strings.Join(tokens, " ")))
+		layout.WriteString("\n")
+	}
+
+	writeLine("score", []string{strconv.Itoa(k.Score)})
+	var stock, waste, foundation []string
+	for _, card := range k.Stock.Cards {
+		stock = append(stock, card.String())
+	}
+	for _, card := range k.Waste {
+		waste = append(waste, card.String())
+	}
+	for _, suit := range klondikeSuits {
+		if pile := k.Foundation.Piles[suit]; len(pile) > 0 {
+			foundation = append(foundation, pile[len(pile)-1].String())
+		}
+	}
+	writeLine("stock", stock)
+	writeLine("waste", waste)
+	writeLine("foundation", foundation)
+	for pileNum, pile := range k.Tableau.Piles {
+		var tokens []string
+		for _, card := range pile {
+			tokens = append(tokens, card.String())
+		}
+		writeLine(strconv.Itoa(pileNum), tokens)
+	}
+	return layout.String()
+}
+
+// ParseKlondikeLayout builds a game from a layout written by Layout.  Blank lines and lines starting with "#" are
+// ignored, the score line is optional, and stock, waste and foundation cards are turned face down or up to match their
+// pile, so they may be written either way.  The game must pass Validate.
+func ParseKlondikeLayout(layout string) (*KlondikeGame, error) {
+	game := new(KlondikeGame)
+	game.Stock = cards.Deck{NumDecks: 1, IsShuffled: true, Cards: []cards.Card{}}
+	game.Waste = []cards.Card{}
+	game.Foundation = *NewFoundation(klondikeSuits)
+	game.Tableau.Piles = [][]*cards.Card{}
+
+	seen := map[string]bool{}
+	for lineNum, line := range strings.Split(layout, "\n") {
+		line = strings.TrimSpace(line)
+		if line == "" || strings.HasPrefix(line, "#") {
+			continue
+		}
+		colon := strings.Index(line, ":")
+		if colon < 0 {
+			return nil, fmt.Errorf("line %d: missing ':'", lineNum+1)
+		}
+		label, tokens := strings.TrimSpace(line[:colon]), strings.Fields(line[colon+1:])
+		if seen[label] {
+			return nil, fmt.Errorf("line %d: %s appears twice", lineNum+1, label)
+		}
+		seen[label] = true
+		if err := game.parseLayoutLine(label, tokens); err != nil {
+			return nil, fmt.Errorf("line %d: %w", lineNum+1, err)
+		}
+	}
+	if err := game.Validate(); err != nil {
+		return nil, err
+	}
+	game.Rehash()
+	return game, nil
+}
+
+func (k *KlondikeGame) parseLayoutLine(label string, tokens []string) error {
+	var parsed []*cards.Card
+	if label != "score" {
+		for _, token := range tokens {
+			card, err := cards.ParseCard(token)
+			if err != nil {
+				return fmt.Errorf("card %q: %w", token, err)
+			}
+			parsed = append(parsed, card)
+		}
+	}
+
+	switch label {
+	case "score":
+		if len(tokens) != 1 {
+			return errors.New("score must be a single number")
+		}
+		score, err := strconv.Atoi(tokens[0])
+		if err != nil {
+			return fmt.Errorf("invalid score %q", tokens[0])
+		}
+		k.Score = score
+	case "stock":
+		for _, card := range parsed {
+			k.Stock.Cards = append(k.Stock.Cards, *card.Conceal())
+		}
+	case "waste":
+		for _, card := range parsed {
+			k.Waste = append(k.Waste, *card.Reveal())
+		}
+	case "foundation":
+		for _, top := range parsed {
+			pile, found := k.Foundation.Piles[top.Suit]
+			if !found {
+				return fmt.Errorf("no foundation pile for %s", top)
+			}
+			if len(pile) > 0 {
+				return fmt.Errorf("more than one %s foundation card", top.Suit)
+			}
+			for _, pip := range pip.All[:PipValue[top.Pip]] {
+				pile = append(pile, cards.Card{Pip: pip, Suit: top.Suit, Revealed: true})
+			}
+			k.Foundation.Piles[top.Suit] = pile
+		}
+	default:
+		pileNum, err := strconv.Atoi(label)
+		if err != nil {
+			return fmt.Errorf("unknown pile %q", label)
+		}
+		if pileNum != len(k.Tableau.Piles) {
+			return fmt.Errorf("tableau pile %d out of order, expected %d", pileNum, len(k.Tableau.Piles))
+		}
+		k.Tableau.Piles = append(k.Tableau.Piles, append(make([]*cards.Card, 0, len(parsed)+13), parsed...))
+	}
+	return nil
+}
@@ -0,0 +1,130 @@
+package solitaire
+
+import (
+	"github.com/jamesboehmer/gopatience/pkg/cards"
+	"github.com/jamesboehmer/gopatience/pkg/cards/pip"
+	"strings"
+	"testing"
+)
+
+const testLayout = `
+# a game with a few cards home
+score: 40
+stock: |3♦ |K♣ |Q♠ |7♥ |4♠ |K♥ |9♥ |10♣ |J♠ |7♠ |8♣ |4♦ |2♦ |J♣ |3♥ |Q♥ |10♠ |2♠ |8♥ |6♠
+waste: 2♣
+foundation: A♥
+0: |K♠ 9♣
+1: 10♥
+2: |3♠ |5♣ 9♦
+3: |K♦ |6♦ |7♦ 8♠
+4: |7♣ |5♦ |J♥ |J♦ 10♦
+5: |A♣ |2♥ |4♥ |6♥ |6♣ A♠
+6: |5♠ |Q♣ |8♦ |4♣ |A♦ |9♠ |Q♦ |3♣ 5♥
+`
+
+// parseTestLayout builds a game from a layout that only lists the cards a test cares about.  Every card it leaves out
+// goes face down at the bottom of the stock, so the game still holds a whole deck.
+func parseTestLayout(t *testing.T, layout string) *KlondikeGame {
+	t.Helper()
+	listed := map[cards.Card]bool{}
+	lines := strings.Split(layout, "\n")
+	stockLine := -1
+	for lineNum, line := range lines {
+		colon := strings.Index(line, ":")
+		if colon < 0 {
+			continue
+		}
+		label := strings.TrimSpace(line[:colon])
+		if label == "stock" {
+			stockLine = lineNum
+		}
+		for _, token := range strings.Fields(line[colon+1:]) {
+			card, err := cards.ParseCard(token)
+			if err != nil {
+				t.Fatalf("card %q: %s", token, err)
+			}
+			pips := []pip.Pip{card.Pip}
+			if label == "foundation" {
+				pips = pip.All[:PipValue[card.Pip]]
+			}
+			for _, pip := range pips {
+				listed[cards.Card{Pip: pip, Suit: card.Suit}] = true
+			}
+		}
+	}
+	var stock []string
+	for _, suit := range klondikeSuits {
+		for _, pip := range pip.All {
+			if card := (cards.Card{Pip: pip, Suit: suit}); !listed[card] {
+				stock = append(stock, card.String())
+			}
+		}
+	}
+	if stockLine < 0 {
+		lines = append(lines, "stock:")
+		stockLine = len(lines) - 1
+	}
+	lines[stockLine] = strings.Join(append([]string{lines[stockLine]}, stock...), " ")
+	k, err := ParseKlondikeLayout(strings.Join(lines, "\n"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	return k
+}
+
+func TestParseKlondikeLayout(t *testing.T) {
+	k, err := ParseKlondikeLayout(testLayout)
+	if err != nil {
+		t.Fatalf("Layout should parse, got %s", err)
+	}
+	if k.Score != 40 || k.Stock.Remaining() != 20 || len(k.Waste) != 1 || len(k.Foundation.Piles["♥"]) != 1 {
+		t.Error("Parsed game should have the layout's score, stock, waste and foundation")
+	}
+	if len(k.Tableau.Piles) != 7 || len(k.Tableau.Piles[6]) != 9 || k.Tableau.Piles[6][7].Revealed ||
+		!k.Tableau.Piles[6][8].Revealed {
+		t.Error("Parsed game should have the layout's tableau")
+	}
+	if err := k.Apply(Move{Type: MoveTableauFoundation, FromPile: 5, CardNum: 5}); err != nil {
+		t.Error("Parsed game should be playable")
+	}
+}
+
+func TestKlondikeGame_LayoutRoundTrip(t *testing.T) {
+	k := NewSeededKlondikeGame(7)
+	for i := 0; i < 60; i++ {
+		layout := k.Layout()
+		parsed, err := ParseKlondikeLayout(layout)
+		if err != nil {
+			t.Fatalf("Layout after %d moves should parse, got %s\n%s", i, err, layout)
+		}
+		if !parsed.Equal(k, HashOptions{}) || parsed.Score != k.Score || parsed.Layout() != layout {
+			t.Fatalf("Layout after %d moves should round trip\n%s", i, layout)
+		}
+		hints := k.Hints()
+		if len(hints) == 0 {
+			break
+		}
+		k.Apply(hints[0].Move)
+	}
+}
+
+func TestParseKlondikeLayout_Errors(t *testing.T) {
+	for name, test := range map[string]struct {
+		layout string
+		err    string
+	}{
+		"missing colon": {"stock |3♦", "line 1: missing ':'"},
+		"bad card":      {"waste: 2x", `line 1: card "2x": invalid suit`},
+		"twice":         {"waste: 2♣\nwaste: 3♣", "line 2: waste appears twice"},
+		"pile order":    {"1: 10♥", "line 1: tableau pile 1 out of order, expected 0"},
+		"unknown":       {"tableux: 10♥", `line 1: unknown pile "tableux"`},
+		"foundation":    {"foundation: A♥ 2♥", "line 1: more than one ♥ foundation card"},
+		"score":         {"score: lots", `line 1: invalid score "lots"`},
+		"duplicate": {strings.Replace(testLayout, "1: 10♥", "1: 10♥ 9♣", 1),
+			"invalid game: 9♣ appears 2 times"},
+	} {
+		if _, err := ParseKlondikeLayout(test.layout); err == nil || !strings.HasPrefix(err.Error(), test.err) {
+			t.Errorf("%s: expected error %q, got %v", name, test.err, err)
+		}
+	}
+}
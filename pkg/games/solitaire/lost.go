@@ -0,0 +1,144 @@
+package solitaire
+
+import (
+	"github.com/jamesboehmer/gopatience/pkg/cards"
+	"github.com/jamesboehmer/gopatience/pkg/cards/pip"
+)
+
+// IsLost reports whether the game can no longer make progress, along with the reason.  A game is lost when no card
+// can go to the foundation, no tableau move turns up a card or opens a column that a king is waiting for, and a full
+// pass through the stock and waste turns up no card that could be played.  Moves that only shuffle cards back and
+// forth don't count, so dealing forever never saves a lost game.
+func (k *KlondikeGame) IsLost() (bool, string) {
+	if k.IsSolved() || k.IsSolvable() {
+		return false, ""
+	}
+	if k.productiveMoves() > 0 {
+		return false, ""
+	}
+	if len(k.LegalMoves()) == 0 {
+		return true, "no moves left"
+	}
+	if k.Stock.Remaining()+len(k.Waste) == 0 {
+		return true, "the stock is empty and no tableau move makes progress"
+	}
+	return true, "no card in the stock or waste can be played and no tableau move makes progress"
+}
+
+// UndoToDecision undoes moves until the game reaches a position with more than one way to make progress, or there is
+// nothing left to undo, and returns how many moves it undid.
+func (k *KlondikeGame) UndoToDecision() int {
+	undone := 0
+	for len(k.UndoStack) > 0 {
+		k.Undo()
+		undone++
+		if k.productiveMoves() > 1 {
+			break
+		}
+	}
+	return undone
+}
+
+// productiveMoves counts the ways the game can make progress: legal tableau and foundation moves that get somewhere,
+// plus every stock and waste card that could be played once it's dealt.
+func (k *KlondikeGame) productiveMoves() int {
+	count := 0
+	for _, move := range k.LegalMoves() {
+		if k.isProductive(move) {
+			count++
+		}
+	}
+	for _, card := range k.Stock.Cards {
+		if k.fitsAnywhere(*card.Reveal()) {
+			count++
+		}
+	}
+	for _, card := range k.Waste {
+		if k.fitsAnywhere(card) {
+			count++
+		}
+	}
+	return count
+}
+
+// isProductive reports whether a legal move gets the game somewhere.  Dealing and playing from the waste are left to
+// productiveMoves, which checks every stock and waste card at once.
+func (k *KlondikeGame) isProductive(move Move) bool {
+	switch move.Type {
+	case MoveTableauFoundation:
+		return true
+	case MoveTableauTableau:
+		card := k.Tableau.Piles[move.FromPile][move.CardNum]
+		if k.revealsCard(move) {
+			return true
+		}
+		if move.CardNum == 0 {
+			return card.Pip != pip.King && k.hasWaitingKing()
+		}
+		below := k.Tableau.Piles[move.FromPile][move.CardNum-1]
+		return k.Foundation.fits(*below) || k.stockFits(below)
+	case MoveFoundationTableau:
+		card := k.movingCard(move)
+		if k.stockFits(card) {
+			return true
+		}
+		// a tableau run that could then build on it and turn up a card
+		for _, pile := range k.Tableau.Piles {
+			for cardNum := 1; cardNum < len(pile); cardNum++ {
+				if !pile[cardNum-1].Revealed && pile[cardNum].Revealed && buildsOn(pile[cardNum], card) {
+					return true
+				}
+			}
+		}
+	}
+	return false
+}
+
+// fitsAnywhere reports whether a card could be played to the foundation or the tableau as things stand.
+func (k *KlondikeGame) fitsAnywhere(card cards.Card) bool {
+	if k.Foundation.fits(card) {
+		return true
+	}
+	for pileNum := range k.Tableau.Piles {
+		if k.Tableau.fits(&card, pileNum) {
+			return true
+		}
+	}
+	return false
+}
+
+// stockFits reports whether any stock or waste card could be built on the given card.
+func (k *KlondikeGame) stockFits(card *cards.Card) bool {
+	for _, stockCard := range k.Stock.Cards {
+		if buildsOn(&stockCard, card) {
+			return true
+		}
+	}
+	for _, wasteCard := range k.Waste {
+		if buildsOn(&wasteCard, card) {
+			return true
+		}
+	}
+	return false
+}
+
+// hasWaitingKing reports whether a king anywhere in the stock, the waste, or on top of face-down tableau cards could
+// use an empty column.
+func (k *KlondikeGame) hasWaitingKing() bool {
+	for _, card := range k.Stock.Cards {
+		if card.Pip == pip.King {
+			return true
+		}
+	}
+	for _, card := range k.Waste {
+		if card.Pip == pip.King {
+			return true
+		}
+	}
+	return k.hasHomelessKing()
+}
+
+// buildsOn reports whether card goes on top of target in the tableau.
+func buildsOn(card *cards.Card, target *cards.Card) bool {
+	return card.Suit.Color() != target.Suit.Color() && PipValue[card.Pip] == PipValue[target.Pip]-1
+}
@@ -0,0 +1,92 @@
+package solitaire
+
+import (
+	"strings"
+	"testing"
+)
+
+// lostLayout can never get anywhere: the aces, queens and red eights are all face down, so nothing left in the stock
+// fits anywhere, and the nines, kings and twos on top have nowhere to go.
+const lostLayout = `
+0: |K♠ 9♣
+1: 2♦
+2: |Q♦ 9♠
+3: |Q♥ |A♠ K♣
+4: |Q♠ |A♣ |8♥ K♥
+5: |Q♣ |A♥ |8♦ K♦
+6: |A♦ 2♥
+`
+
+// decisionLayout is lostLayout with a 10♥ that both black nines want, and moving either of them turns up a card.
+var decisionLayout = strings.Replace(lostLayout, "1: 2♦", "1: 10♥", 1)
+
+func TestKlondikeGame_IsLost(t *testing.T) {
+	k := parseTestLayout(t, lostLayout)
+	lost, reason := k.IsLost()
+	if !lost {
+		t.Fatal("Game with no productive moves should be lost")
+	}
+	if reason != "no card in the stock or waste can be played and no tableau move makes progress" {
+		t.Errorf("Unexpected reason %q", reason)
+	}
+	// dealing doesn't change anything
+	k.Deal()
+	if lost, _ := k.IsLost(); !lost {
+		t.Error("Dealing should not save a lost game")
+	}
+}
+
+func TestKlondikeGame_IsLostNoMoves(t *testing.T) {
+	// lostLayout with the stock dealt face down under the piles
+	k := parseTestLayout(t, `
+0: |3♠ |3♣ |3♥ |3♦ |K♠ 9♣
+1: |4♠ |4♣ |4♥ |4♦ 2♦
+2: |5♠ |5♣ |5♥ |5♦ |Q♦ 9♠
+3: |6♠ |6♣ |6♥ |6♦ |Q♥ |A♠ K♣
+4: |7♠ |7♣ |7♥ |7♦ |Q♠ |A♣ |8♥ K♥
+5: |8♠ |8♣ |9♥ |9♦ |10♠ |10♣ |Q♣ |A♥ |8♦ K♦
+6: |10♥ |10♦ |J♠ |J♣ |J♥ |J♦ |2♠ |2♣ |A♦ 2♥
+`)
+	if lost, reason := k.IsLost(); !lost || reason != "no moves left" {
+		t.Errorf("Game with no legal moves should be lost with no moves left, got %v %q", lost, reason)
+	}
+}
+
+func TestKlondikeGame_IsNotLost(t *testing.T) {
+	// a stock card that fits on the tableau
+	k := parseTestLayout(t, strings.Replace(lostLayout, "|8♥ ", "", 1))
+	if lost, _ := k.IsLost(); lost {
+		t.Error("Game should not be lost while a stock card can be played")
+	}
+	// a tableau move that reveals a card
+	k = parseTestLayout(t, decisionLayout)
+	if lost, _ := k.IsLost(); lost {
+		t.Error("Game should not be lost while a tableau move reveals a card")
+	}
+	// a solvable game
+	k = parseTestLayout(t, "foundation: K♥ K♦ K♣ Q♠\n0: K♠")
+	if lost, _ := k.IsLost(); lost {
+		t.Error("Solvable game should not be lost")
+	}
+}
+
+func TestKlondikeGame_UndoToDecision(t *testing.T) {
+	k := parseTestLayout(t, decisionLayout)
+	if k.productiveMoves() != 2 {
+		t.Fatalf("Expected 2 productive moves, got %d", k.productiveMoves())
+	}
+	k.SelectTableau(0, -1, 1)
+	k.Deal()
+	if lost, _ := k.IsLost(); !lost {
+		t.Fatal("Game should be lost after covering the only 10")
+	}
+	if undone := k.UndoToDecision(); undone != 2 {
+		t.Errorf("Expected 2 moves undone, got %d", undone)
+	}
+	if len(k.Tableau.Piles[1]) != 1 || len(k.Waste) != 0 {
+		t.Error("UndoToDecision should return to the position before the 9♣ was moved")
+	}
+	if undone := k.UndoToDecision(); undone != 0 {
+		t.Errorf("UndoToDecision with nothing to undo should undo nothing, got %d", undone)
+	}
+}
@@ -0,0 +1,97 @@
+package solitaire
+
+import (
+	"bytes"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"github.com/jamesboehmer/gopatience/pkg/games/util"
+	"io/ioutil"
+)
+
+// saveMigrations upgrades a decoded save from the version it's keyed by to the next version.  Loading runs them in
+// order, so a save from any older version ends up in the current format.
+var saveMigrations = map[int]func(save map[string]interface{}) error{
+	// version 2 moved the options into their own object and added the move history
+	1: func(save map[string]interface{}) error {
+		save["options"] = map[string]interface{}{"autoplay": save["autoplay"]}
+		delete(save, "autoplay")
+		save["history"] = []interface{}{}
+		return nil
+	},
+	// version 3 added the variant and the time of the save, which is left unset for older saves
+	2: func(save map[string]interface{}) error {
+		save["variant"] = KlondikeVariant
+		return nil
+	},
+	// version 4 added the signature and the unranked flag, which older saves never have
+	3: func(save map[string]interface{}) error {
+		return nil
+	},
+	// version 5 added the deal code, which older saves can only get back from their seed
+	4: func(save map[string]interface{}) error {
+		return nil
+	},
+	// version 6 added the destination policy option, which older saves leave at the default
+	5: func(save map[string]interface{}) error {
+		return nil
+	},
+}
+
+// migrateSave upgrades raw save data to KlondikeSaveVersion, and returns it along with the version it started at.
+func migrateSave(data []byte) ([]byte, int, error) {
+	var save map[string]interface{}
+	if err := json.Unmarshal(data, &save); err != nil {
+		return nil, 0, fmt.Errorf("invalid save: %w", err)
+	}
+	number, ok := save["version"].(float64)
+	if !ok || number < 1 || number != float64(int(number)) {
+		return nil, 0, errors.New("invalid save: no version")
+	}
+	version := int(number)
+	if version > KlondikeSaveVersion {
+		return nil, 0, fmt.Errorf("unsupported save version %d, expected %d or older", version, KlondikeSaveVersion)
+	}
+	if version == KlondikeSaveVersion {
+		return data, version, nil
+	}
+	for from := version; from < KlondikeSaveVersion; from++ {
+		migrate, found := saveMigrations[from]
+		if !found {
+			return nil, 0, fmt.Errorf("unsupported save version %d, no migration to version %d", from, from+1)
+		}
+		if err := migrate(save); err != nil {
+			return nil, 0, fmt.Errorf("migrating save from version %d: %w", from, err)
+		}
+		save["version"] = from + 1
+	}
+	data, err := json.Marshal(save)
+	return data, version, err
+}
+
+// UpgradeKlondikeFile rewrites an older save at path in the current format, after copying the original to path plus
+// ".bak".  The rewritten save keeps the time it was originally saved, and the backups SaveFile keeps are left alone,
+// since the game itself hasn't changed.  It returns the version the file was at, and leaves files that are already
+// current untouched.
+func UpgradeKlondikeFile(path string) (int, error) {
+	data, err := ioutil.ReadFile(path)
+	if err != nil {
+		return 0, err
+	}
+	save, version, err := readSave(bytes.NewReader(data))
+	if err != nil || version == KlondikeSaveVersion {
+		return version, err
+	}
+	game, err := save.game()
+	if err != nil {
+		return version, err
+	}
+	upgraded, err := game.encode(save.Saved)
+	if err != nil {
+		return version, err
+	}
+	if err := util.WriteFileAtomic(path+".bak", data, 0644); err != nil {
+		return version, err
+	}
+	return version, util.WriteFileAtomic(path, upgraded, 0644)
+}
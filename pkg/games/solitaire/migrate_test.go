@@ -0,0 +1,111 @@
+package solitaire
+
+import (
+	"fmt"
+	"github.com/jamesboehmer/gopatience/pkg/games/util"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+	"time"
+)
+
+const versionOneSave = `{
+  "version": 1,
+  "seed": 7,
+  "score": 15,
+  "autoplay": true,
+  "stock": ["|3♦", "|K♣", "|Q♠", "|7♥", "|4♠", "|K♥", "|9♥", "|10♣", "|J♠", "|7♠", "|8♣", "|4♦", "|2♦", "|J♣", "|3♥", "|Q♥", "|10♠", "|2♠", "|8♥", "|6♠"],
+  "waste": ["2♣"],
+  "foundation": {"♥": ["A♥"], "♦": [], "♣": [], "♠": []},
+  "tableau": [
+    ["|K♠", "9♣"],
+    ["10♥"],
+    ["|3♠", "|5♣", "9♦"],
+    ["|K♦", "|6♦", "|7♦", "8♠"],
+    ["|7♣", "|5♦", "|J♥", "|J♦", "10♦"],
+    ["|A♣", "|2♥", "|4♥", "|6♥", "|6♣", "A♠"],
+    ["|5♠", "|Q♣", "|8♦", "|4♣", "|A♦", "|9♠", "|Q♦", "|3♣", "5♥"]
+  ]
+}
+`
+
+func TestLoadKlondikeGame_Migrate(t *testing.T) {
+	k, err := LoadKlondikeGame(strings.NewReader(versionOneSave))
+	if err != nil {
+		t.Fatalf("Version 1 saves should load, got %s", err)
+	}
+	if k.Seed != 7 || k.Score != 15 || !k.AutoPlay {
+		t.Error("Migrated save should keep its seed, score and options")
+	}
+	if k.Stock.Remaining() != 20 || len(k.Waste) != 1 || len(k.Foundation.Piles["♥"]) != 1 ||
+		len(k.Tableau.Piles[0]) != 2 || k.Tableau.Piles[0][0].Revealed {
+		t.Error("Migrated save should keep its cards")
+	}
+	if len(k.History) != 0 {
+		t.Error("Migrated save should start with an empty history")
+	}
+}
+
+func TestUpgradeKlondikeFile(t *testing.T) {
+	dir, err := ioutil.TempDir("", "gopatience")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer os.RemoveAll(dir)
+	path := filepath.Join(dir, "klondike.save")
+	ioutil.WriteFile(path, []byte(versionOneSave), 0644)
+
+	version, err := UpgradeKlondikeFile(path)
+	if err != nil || version != 1 {
+		t.Fatalf("Upgrade should report version 1, got %d %v", version, err)
+	}
+	backup, _ := ioutil.ReadFile(path + ".bak")
+	if string(backup) != versionOneSave {
+		t.Error("Upgrade should back up the original save")
+	}
+	upgraded, _ := ioutil.ReadFile(path)
+	if !strings.Contains(string(upgraded), fmt.Sprintf(`"version": %d`, KlondikeSaveVersion)) {
+		t.Error("Upgrade should rewrite the save in the current version")
+	}
+	if _, err := os.Stat(util.BackupPath(path, 1)); err == nil {
+		t.Error("Upgrade should not rotate the save's backups")
+	}
+
+	os.Remove(path + ".bak")
+	if version, err := UpgradeKlondikeFile(path); err != nil || version != KlondikeSaveVersion {
+		t.Errorf("Upgrading a current save should report the current version, got %d %v", version, err)
+	}
+	if _, err := os.Stat(path + ".bak"); err == nil {
+		t.Error("Upgrading a current save should not make a backup")
+	}
+}
+
+func TestUpgradeKlondikeFile_KeepsSaved(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "klondike.save")
+	saved := time.Date(2020, time.March, 1, 12, 0, 0, 0, time.UTC)
+	data, err := NewSeededKlondikeGame(7).encode(saved)
+	if err != nil {
+		t.Fatal(err)
+	}
+	current := fmt.Sprintf(`"version": %d`, KlondikeSaveVersion)
+	older := fmt.Sprintf(`"version": %d`, KlondikeSaveVersion-1)
+	ioutil.WriteFile(path, []byte(strings.Replace(string(data), current, older, 1)), 0644)
+
+	if _, err := UpgradeKlondikeFile(path); err != nil {
+		t.Fatal(err)
+	}
+	file, err := os.Open(path)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer file.Close()
+	save, version, err := readSave(file)
+	if err != nil || version != KlondikeSaveVersion {
+		t.Fatalf("Upgraded save should be current, got %d %v", version, err)
+	}
+	if !save.Saved.Equal(saved) {
+		t.Errorf("Upgrade should keep the time the game was saved, %s, got %s", saved, save.Saved)
+	}
+}
@@ -0,0 +1,254 @@
+package solitaire
+
+import (
+	"fmt"
+	"github.com/jamesboehmer/gopatience/pkg/cards"
+	"github.com/jamesboehmer/gopatience/pkg/cards/suit"
+	"github.com/jamesboehmer/gopatience/pkg/games/util"
+)
+
+type MoveType int
+
+const (
+	MoveDeal MoveType = iota
+	MoveWasteFoundation
+	MoveWasteTableau
+	MoveTableauFoundation
+	MoveTableauTableau
+	MoveFoundationTableau
+)
+
+// A Move is a single klondike play with an explicit source and destination.  FromPile and CardNum locate the source
+// cards of tableau moves, Suit names the source pile of foundation moves, and ToPile is the destination of any move
+// onto the tableau.  Fields that don't apply to the move's type are ignored.  Moves are saved in the game history, so
+// the MoveType values must never change.
+type Move struct {
+	Type     MoveType  `json:"type,omitempty"`
+	FromPile int       `json:"from,omitempty"`
+	CardNum  int       `json:"card,omitempty"`
+	Suit     suit.Suit `json:"suit,omitempty"`
+	ToPile   int       `json:"to,omitempty"`
+}
+
+func (m Move) String() string {
+	switch m.Type {
+	case MoveDeal:
+		return "deal"
+	case MoveWasteFoundation:
+		return "waste to foundation"
+	case MoveWasteTableau:
+		return fmt.Sprintf("waste to tableau %d", m.ToPile)
+	case MoveTableauFoundation:
+		return fmt.Sprintf("tableau %d to foundation", m.FromPile)
+	case MoveTableauTableau:
+		return fmt.Sprintf("tableau %d card %d to tableau %d", m.FromPile, m.CardNum, m.ToPile)
+	case MoveFoundationTableau:
+		return fmt.Sprintf("foundation %s to tableau %d", m.Suit, m.ToPile)
+	}
+	return "unknown move"
+}
+
+var suitCommands = map[suit.Suit]string{suit.Clubs: "c", suit.Diamonds: "d", suit.Hearts: "h", suit.Spades: "s"}
+
+// Command spells the move in the klondike CLI's command vocabulary, so that replaying it through SelectWaste,
+// SelectTableau and SelectFoundation makes exactly this move.
+func (m Move) Command() string {
+	switch m.Type {
+	case MoveDeal:
+		return "d"
+	case MoveWasteFoundation:
+		return "w"
+	case MoveWasteTableau:
+		return fmt.Sprintf("w %d", m.ToPile)
+	case MoveTableauFoundation:
+		return fmt.Sprintf("t %d", m.FromPile)
+	case MoveTableauTableau:
+		return fmt.Sprintf("t %d %d %d", m.FromPile, m.CardNum, m.ToPile)
+	case MoveFoundationTableau:
+		return fmt.Sprintf("f %s %d", suitCommands[m.Suit], m.ToPile)
+	}
+	return ""
+}
+
+// IsLegal reports whether Apply would accept the move.  It checks the same rules as Apply without building an error
+// to say why not, so it's cheap enough to call on every candidate move.
+func (k *KlondikeGame) IsLegal(move Move) bool {
+	switch move.Type {
+	case MoveDeal:
+		return k.Stock.Remaining()+len(k.Waste) > 0
+	case MoveWasteFoundation:
+		return len(k.Waste) > 0 && k.Foundation.fits(k.Waste[len(k.Waste)-1])
+	case MoveWasteTableau:
+		return len(k.Waste) > 0 && k.Tableau.fits(&k.Waste[len(k.Waste)-1], move.ToPile)
+	case MoveTableauFoundation:
+		return k.Tableau.canGet(move.FromPile, move.CardNum) &&
+			move.CardNum == len(k.Tableau.Piles[move.FromPile])-1 &&
+			k.Foundation.fits(*k.Tableau.Piles[move.FromPile][move.CardNum])
+	case MoveTableauTableau:
+		return k.Tableau.canGet(move.FromPile, move.CardNum) && move.FromPile != move.ToPile &&
+			k.Tableau.fits(k.Tableau.Piles[move.FromPile][move.CardNum], move.ToPile)
+	case MoveFoundationTableau:
+		if !k.Foundation.canGet(move.Suit) {
+			return false
+		}
+		pile := k.Foundation.Piles[move.Suit]
+		return k.Tableau.fits(&pile[len(pile)-1], move.ToPile)
+	}
+	return false
+}
+
+func (k *KlondikeGame) checkMove(move Move) error {
+	switch move.Type {
+	case MoveDeal:
+		if k.Stock.Remaining()+len(k.Waste) == 0 {
+			return ErrNoCardsRemaining
+		}
+		return nil
+	case MoveWasteFoundation:
+		if len(k.Waste) == 0 {
+			return ErrEmptyPile
+		}
+		return k.Foundation.checkPut(k.Waste[len(k.Waste)-1])
+	case MoveWasteTableau:
+		if len(k.Waste) == 0 {
+			return ErrEmptyPile
+		}
+		topCard := k.Waste[len(k.Waste)-1]
+		return k.Tableau.checkPut([]*cards.Card{&topCard}, move.ToPile)
+	case MoveTableauFoundation:
+		if err := k.Tableau.checkGet(move.FromPile, move.CardNum); err != nil {
+			return err
+		}
+		if move.CardNum != len(k.Tableau.Piles[move.FromPile])-1 {
+			return errNotTopCard
+		}
+		return k.Foundation.checkPut(*k.Tableau.Piles[move.FromPile][move.CardNum])
+	case MoveTableauTableau:
+		if err := k.Tableau.checkGet(move.FromPile, move.CardNum); err != nil {
+			return err
+		}
+		if move.FromPile == move.ToPile {
+			return ErrInvalidPile
+		}
+		return k.Tableau.checkPut(k.Tableau.Piles[move.FromPile][move.CardNum:], move.ToPile)
+	case MoveFoundationTableau:
+		if err := k.Foundation.checkGet(move.Suit); err != nil {
+			return err
+		}
+		pile := k.Foundation.Piles[move.Suit]
+		topCard := pile[len(pile)-1]
+		return k.Tableau.checkPut([]*cards.Card{&topCard}, move.ToPile)
+	}
+	return ErrIllegalMove
+}
+
+// Apply makes a single move and records it as one undoable action, or returns an error and leaves the game untouched
+// if the move is illegal.  With AutoPlay on, every card that's then safe to send home follows as its own undoable move.
+func (k *KlondikeGame) Apply(move Move) error {
+	if err := k.apply(move); err != nil {
+		return err
+	}
+	if k.AutoPlay {
+		k.PlaySafe()
+	}
+	return nil
+}
+
+func (k *KlondikeGame) apply(move Move) error {
+	if err := k.checkMove(move); err != nil {
+		return located(err, "%s", move)
+	}
+	valid := debugValidation && k.isValid()
+	switch move.Type {
+	case MoveDeal:
+		k.deal()
+	case MoveWasteFoundation:
+		topCard := k.popWaste()
+		k.Foundation.Put(topCard)
+		k.adjustScore(PointsWasteFoundation)
+		k.UndoStack = append(k.UndoStack, util.UndoAction{
+			Function: k.undoSelectWaste,
+			Args:     []interface{}{true, topCard},
+		})
+	case MoveWasteTableau:
+		topCard := k.popWaste()
+		k.Tableau.Put([]*cards.Card{&topCard}, move.ToPile)
+		k.adjustScore(PointsWasteTableau)
+		k.UndoStack = append(k.UndoStack, util.UndoAction{
+			Function: k.undoSelectWaste,
+			Args:     []interface{}{false, topCard},
+		})
+	case MoveTableauFoundation:
+		selected, _ := k.Tableau.Get(move.FromPile, move.CardNum)
+		k.Foundation.Put(*selected[0])
+		k.adjustScore(PointsTableauFoundation)
+		k.UndoStack = append(k.UndoStack, util.UndoAction{
+			Function: k.undoSelectTableau,
+			Args:     []interface{}{true},
+		})
+	case MoveTableauTableau:
+		selected, _ := k.Tableau.Get(move.FromPile, move.CardNum)
+		k.Tableau.Put(selected, move.ToPile)
+		k.adjustScore(PointsWasteTableau)
+		k.UndoStack = append(k.UndoStack, util.UndoAction{
+			Function: k.undoSelectTableau,
+			Args:     []interface{}{false},
+		})
+	case MoveFoundationTableau:
+		card, _ := k.Foundation.Get(move.Suit)
+		k.Tableau.Put([]*cards.Card{card}, move.ToPile)
+		k.adjustScore(-PointsTableauFoundation)
+		k.UndoStack = append(k.UndoStack, util.UndoAction{
+			Function: k.undoSelectFoundation,
+			Args:     nil,
+		})
+	}
+	k.History = append(k.History, move)
+	if valid {
+		k.mustStayValid(move.String())
+	}
+	return nil
+}
+
+func (k *KlondikeGame) popWaste() cards.Card {
+	topCard := k.Waste[len(k.Waste)-1]
+	k.Waste = k.Waste[:len(k.Waste)-1]
+	return topCard
+}
+
+// LegalMoves lists every move that Apply would accept in the current position, without changing the game.  Moves to
+// the foundation come first, then tableau builds (including partial runs), foundation moves back to the tableau, and
+// finally the deal.
+func (k *KlondikeGame) LegalMoves() []Move {
+	var candidates []Move
+	for pileNum, pile := range k.Tableau.Piles {
+		candidates = append(candidates, Move{Type: MoveTableauFoundation, FromPile: pileNum, CardNum: len(pile) - 1})
+	}
+	candidates = append(candidates, Move{Type: MoveWasteFoundation})
+	for pileNum, pile := range k.Tableau.Piles {
+		for cardNum := range pile {
+			for destination := range k.Tableau.Piles {
+				candidates = append(candidates, Move{
+					Type: MoveTableauTableau, FromPile: pileNum, CardNum: cardNum, ToPile: destination,
+				})
+			}
+		}
+	}
+	for destination := range k.Tableau.Piles {
+		candidates = append(candidates, Move{Type: MoveWasteTableau, ToPile: destination})
+	}
+	for _, suit := range klondikeSuits {
+		for destination := range k.Tableau.Piles {
+			candidates = append(candidates, Move{Type: MoveFoundationTableau, Suit: suit, ToPile: destination})
+		}
+	}
+	candidates = append(candidates, Move{Type: MoveDeal})
+
+	var moves []Move
+	for _, move := range candidates {
+		if k.IsLegal(move) {
+			moves = append(moves, move)
+		}
+	}
+	return moves
+}
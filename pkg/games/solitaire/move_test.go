@@ -0,0 +1,242 @@
+package solitaire
+
+import (
+	"github.com/jamesboehmer/gopatience/pkg/cards"
+	"github.com/jamesboehmer/gopatience/pkg/cards/suit"
+	"testing"
+)
+
+func TestKlondikeGame_IsLegal(t *testing.T) {
+	k := NewKlondikeGame()
+	for pileNum, cardString := range []string{"10♦", "9♠", "J♦", "6♣", "3♦", "9♥", "2♦"} {
+		k.Tableau.Piles[pileNum][len(k.Tableau.Piles[pileNum])-1], _ = cards.ParseCard(cardString)
+	}
+	if !k.IsLegal(Move{Type: MoveDeal}) {
+		t.Error("Dealing from a full stock should be legal")
+	}
+	if k.IsLegal(Move{Type: MoveWasteFoundation}) || k.IsLegal(Move{Type: MoveWasteTableau, ToPile: 0}) {
+		t.Error("Moves from an empty waste should be illegal")
+	}
+	if !k.IsLegal(Move{Type: MoveTableauTableau, FromPile: 1, CardNum: 1, ToPile: 0}) {
+		t.Error("9♠ on 10♦ should be legal")
+	}
+	if k.IsLegal(Move{Type: MoveTableauTableau, FromPile: 1, CardNum: 1, ToPile: 1}) {
+		t.Error("Moving a card onto its own pile should be illegal")
+	}
+	if k.IsLegal(Move{Type: MoveTableauTableau, FromPile: 1, CardNum: 0, ToPile: 0}) {
+		t.Error("Moving a concealed card should be illegal")
+	}
+	if k.IsLegal(Move{Type: MoveTableauTableau, FromPile: 5, CardNum: 5, ToPile: 0}) {
+		t.Error("9♥ on 10♦ should be illegal")
+	}
+	if k.IsLegal(Move{Type: MoveTableauFoundation, FromPile: 6, CardNum: 6}) {
+		t.Error("2♦ on an empty foundation should be illegal")
+	}
+	if k.IsLegal(Move{Type: MoveFoundationTableau, Suit: suit.Hearts, ToPile: 3}) {
+		t.Error("Moving from an empty foundation pile should be illegal")
+	}
+	if k.IsLegal(Move{Type: -1}) {
+		t.Error("Unknown move types should be illegal")
+	}
+	if len(k.UndoStack)+len(k.Tableau.UndoStack)+len(k.Foundation.UndoStack) != 0 {
+		t.Error("Checking moves should not change the game")
+	}
+}
+
+func TestKlondikeGame_IsLegalMatchesApply(t *testing.T) {
+	for _, k := range benchmarkGames() {
+		var candidates []Move
+		for _, moveType := range []MoveType{MoveDeal, MoveWasteFoundation, MoveWasteTableau, MoveTableauFoundation,
+			MoveTableauTableau, MoveFoundationTableau} {
+			for from := -1; from <= len(k.Tableau.Piles); from++ {
+				for cardNum := -1; cardNum < 20; cardNum++ {
+					for to := -1; to <= len(k.Tableau.Piles); to++ {
+						candidates = append(candidates, Move{
+							Type: moveType, FromPile: from, CardNum: cardNum, Suit: klondikeSuits[cardNum&3], ToPile: to,
+						})
+					}
+				}
+			}
+		}
+		for _, move := range candidates {
+			if legal, err := k.IsLegal(move), k.checkMove(move); legal != (err == nil) {
+				t.Fatalf("IsLegal says %t for %s, but Apply says %v", legal, move, err)
+			}
+		}
+		checkAll := func() {
+			for _, move := range candidates {
+				k.IsLegal(move)
+			}
+		}
+		if allocs := testing.AllocsPerRun(1, checkAll); allocs != 0 {
+			t.Errorf("IsLegal should not allocate, got %.0f allocations", allocs)
+		}
+	}
+}
+
+func TestKlondikeGame_ApplyIllegal(t *testing.T) {
+	k := NewKlondikeGame()
+	if k.Apply(Move{Type: MoveTableauFoundation, FromPile: 0, CardNum: 1}) == nil {
+		t.Error("Should have returned an error for an invalid card number")
+	}
+	if len(k.UndoStack) != 0 || len(k.Tableau.Piles[0]) != 1 {
+		t.Error("An illegal move should not change the game")
+	}
+}
+
+func TestKlondikeGame_ApplyTableauFoundation(t *testing.T) {
+	k := NewKlondikeGame()
+	k.Tableau.Piles[1][1], _ = cards.ParseCard("A♣")
+	if k.Apply(Move{Type: MoveTableauFoundation, FromPile: 1, CardNum: 1}) != nil {
+		t.Error("A♣ should move to the foundation")
+	}
+	if len(k.Tableau.Piles[1]) != 1 || !k.Tableau.Piles[1][0].Revealed {
+		t.Error("The card below A♣ should be revealed")
+	}
+	if k.Score != PointsTableauFoundation {
+		t.Errorf("The score should be %d", PointsTableauFoundation)
+	}
+	k.Undo()
+	if len(k.Tableau.Piles[1]) != 2 || k.Tableau.Piles[1][0].Revealed {
+		t.Error("Undo should put A♣ back on the tableau and conceal the card below it")
+	}
+	if len(k.Foundation.Piles[suit.Clubs]) != 0 {
+		t.Error("Undo should remove A♣ from the foundation")
+	}
+	if k.Score != 0 {
+		t.Error("The score should be reset")
+	}
+}
+
+func TestKlondikeGame_ApplyTableauTableau(t *testing.T) {
+	k := NewKlondikeGame()
+	for pileNum, cardString := range []string{"10♦", "8♥", "J♦", "6♣", "3♦", "9♥", "2♦"} {
+		k.Tableau.Piles[pileNum][len(k.Tableau.Piles[pileNum])-1], _ = cards.ParseCard(cardString)
+	}
+	k.Tableau.Piles[1][0], _ = cards.ParseCard("9♠")
+	if k.Apply(Move{Type: MoveTableauTableau, FromPile: 1, CardNum: 0, ToPile: 0}) != nil {
+		t.Error("9♠ 8♥ should move onto 10♦")
+	}
+	if len(k.Tableau.Piles[0]) != 3 || len(k.Tableau.Piles[1]) != 0 {
+		t.Error("Both cards should have moved")
+	}
+	// a later build on the emptied pile must not disturb the recorded move
+	king, _ := cards.ParseCard("K♠")
+	k.Tableau.Put([]*cards.Card{king}, 1)
+	k.Tableau.Undo()
+	k.Undo()
+	if len(k.Tableau.Piles[0]) != 1 || len(k.Tableau.Piles[1]) != 2 {
+		t.Error("Undo should move both cards back")
+	}
+	if k.Tableau.Piles[1][0].String() != "9♠" || k.Tableau.Piles[1][1].String() != "8♥" {
+		t.Error("Undo should restore the original cards")
+	}
+	if k.Score != 0 {
+		t.Error("The score should be reset")
+	}
+}
+
+func TestKlondikeGame_ApplyFoundationTableau(t *testing.T) {
+	k := NewKlondikeGame()
+	k.Tableau.Piles[3][3], _ = cards.ParseCard("6♣")
+	card, _ := cards.ParseCard("5♥")
+	k.Foundation.Piles[suit.Hearts] = append(k.Foundation.Piles[suit.Hearts], *card)
+	if k.Apply(Move{Type: MoveFoundationTableau, Suit: suit.Hearts, ToPile: 3}) != nil {
+		t.Error("5♥ should move onto 6♣")
+	}
+	if len(k.Foundation.Piles[suit.Hearts]) != 0 || len(k.Tableau.Piles[3]) != 5 {
+		t.Error("5♥ should have left the foundation for the tableau")
+	}
+	if k.Score != -PointsTableauFoundation {
+		t.Errorf("The score should be %d", -PointsTableauFoundation)
+	}
+	k.Undo()
+	if len(k.Foundation.Piles[suit.Hearts]) != 1 || len(k.Tableau.Piles[3]) != 4 {
+		t.Error("Undo should put 5♥ back on the foundation")
+	}
+}
+
+func TestMove_String(t *testing.T) {
+	for expected, move := range map[string]Move{
+		"deal":                          {Type: MoveDeal},
+		"waste to foundation":           {Type: MoveWasteFoundation},
+		"waste to tableau 3":            {Type: MoveWasteTableau, ToPile: 3},
+		"tableau 2 to foundation":       {Type: MoveTableauFoundation, FromPile: 2, CardNum: 4},
+		"tableau 2 card 4 to tableau 5": {Type: MoveTableauTableau, FromPile: 2, CardNum: 4, ToPile: 5},
+		"foundation ♥ to tableau 1":     {Type: MoveFoundationTableau, Suit: suit.Hearts, ToPile: 1},
+	} {
+		if move.String() != expected {
+			t.Errorf("Expected %q, got %q", expected, move.String())
+		}
+	}
+}
+
+func TestMove_Command(t *testing.T) {
+	for expected, move := range map[string]Move{
+		"d":       {Type: MoveDeal},
+		"w":       {Type: MoveWasteFoundation},
+		"w 3":     {Type: MoveWasteTableau, ToPile: 3},
+		"t 2":     {Type: MoveTableauFoundation, FromPile: 2, CardNum: 4},
+		"t 2 4 5": {Type: MoveTableauTableau, FromPile: 2, CardNum: 4, ToPile: 5},
+		"f h 1":   {Type: MoveFoundationTableau, Suit: suit.Hearts, ToPile: 1},
+	} {
+		if move.Command() != expected {
+			t.Errorf("Expected %q, got %q", expected, move.Command())
+		}
+	}
+}
+
+func TestKlondikeGame_LegalMoves(t *testing.T) {
+	k := NewKlondikeGame()
+	k.Stock.Cards = []cards.Card{}
+	for pileNum, pile := range [][]string{{"10♦"}, {"|3♠", "9♠", "8♥"}, {"A♣"}, {}, {}, {}, {}} {
+		k.Tableau.Piles[pileNum] = []*cards.Card{}
+		for _, cardString := range pile {
+			card, _ := cards.ParseCard(cardString)
+			k.Tableau.Piles[pileNum] = append(k.Tableau.Piles[pileNum], card)
+		}
+	}
+	card, _ := cards.ParseCard("K♥")
+	k.Waste = []cards.Card{*card}
+	card, _ = cards.ParseCard("A♥")
+	k.Foundation.Piles[suit.Hearts] = []cards.Card{*card}
+
+	expected := []Move{
+		{Type: MoveTableauFoundation, FromPile: 2, CardNum: 0},
+		{Type: MoveTableauTableau, FromPile: 1, CardNum: 1, ToPile: 0},
+		{Type: MoveWasteTableau, ToPile: 3},
+		{Type: MoveWasteTableau, ToPile: 4},
+		{Type: MoveWasteTableau, ToPile: 5},
+		{Type: MoveWasteTableau, ToPile: 6},
+		{Type: MoveDeal},
+	}
+	moves := k.LegalMoves()
+	if len(moves) != len(expected) {
+		t.Fatalf("Expected %d legal moves, got %d: %v", len(expected), len(moves), moves)
+	}
+	for i, move := range moves {
+		if move != expected[i] {
+			t.Errorf("Expected move %d to be %s, got %s", i, expected[i], move)
+		}
+	}
+	if len(k.Tableau.Piles[1]) != 3 || len(k.Waste) != 1 || len(k.UndoStack) != 0 {
+		t.Error("Listing legal moves should not change the game")
+	}
+	for _, move := range moves {
+		if k.Apply(move) != nil {
+			t.Errorf("%s should have been applied", move)
+		}
+		k.Undo()
+	}
+}
+
+func TestKlondikeGame_LegalMovesNoCards(t *testing.T) {
+	k := NewKlondikeGame()
+	k.Stock.Cards = []cards.Card{}
+	for pileNum := range k.Tableau.Piles {
+		k.Tableau.Piles[pileNum] = []*cards.Card{}
+	}
+	if len(k.LegalMoves()) != 0 {
+		t.Error("There should be no legal moves without any cards")
+	}
+}
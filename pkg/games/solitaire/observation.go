@@ -0,0 +1,79 @@
+package solitaire
+
+import (
+	"encoding/json"
+	"io"
+)
+
+// ObservationVersion is the version of the Observation format, which only changes when the format does.
+const ObservationVersion = 1
+
+// HiddenCard stands in for a face-down tableau card in an Observation.
+const HiddenCard = "#"
+
+// An Observation is what a player can see of a game, for clients and bots that mustn't see any more: face-down
+// tableau cards are HiddenCard, and the stock is only a count.  The waste and foundation are shown in full, since a
+// player has seen every card in them.  Its JSON is stable: fields always come in the same order, foundation suits are
+// sorted, and empty piles are [] rather than null, so equal observations encode to the same bytes.
+type Observation struct {
+	Version    int                 `json:"version"`
+	Variant    string              `json:"variant"`
+	Score      int                 `json:"score"`
+	Unranked   bool                `json:"unranked"`
+	AutoPlay   bool                `json:"autoplay"`
+	Moves      int                 `json:"moves"`
+	Stock      int                 `json:"stock"`
+	Waste      []string            `json:"waste"`
+	Foundation map[string][]string `json:"foundation"`
+	Tableau    [][]string          `json:"tableau"`
+}
+
+// Observation returns what a player can see of the game.
+func (k *KlondikeGame) Observation() *Observation {
+	observation := &Observation{
+		Version:    ObservationVersion,
+		Variant:    KlondikeVariant,
+		Score:      k.Score,
+		Unranked:   k.Unranked,
+		AutoPlay:   k.AutoPlay,
+		Moves:      len(k.History),
+		Stock:      k.Stock.Remaining(),
+		Waste:      []string{},
+		Foundation: make(map[string][]string, len(k.Foundation.Piles)),
+		Tableau:    make([][]string, 0, len(k.Tableau.Piles)),
+	}
+	for _, card := range k.Waste {
+		card.Revealed = true
+		observation.Waste = append(observation.Waste, card.String())
+	}
+	for suit, pile := range k.Foundation.Piles {
+		cards := []string{}
+		for _, card := range pile {
+			card.Revealed = true
+			cards = append(cards, card.String())
+		}
+		observation.Foundation[string(suit)] = cards
+	}
+	for _, pile := range k.Tableau.Piles {
+		cards := []string{}
+		for _, card := range pile {
+			if card.Revealed {
+				cards = append(cards, card.String())
+			} else {
+				cards = append(cards, HiddenCard)
+			}
+		}
+		observation.Tableau = append(observation.Tableau, cards)
+	}
+	return observation
+}
+
+// Write writes the observation to w as indented JSON.
+func (o *Observation) Write(w io.Writer) error {
+	data, err := json.MarshalIndent(o, "", "  ")
+	if err != nil {
+		return err
+	}
+	_, err = w.Write(append(data, '\n'))
+	return err
+}
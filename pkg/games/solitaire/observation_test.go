@@ -0,0 +1,67 @@
+package solitaire
+
+import (
+	"bytes"
+	"encoding/json"
+	"strings"
+	"testing"
+)
+
+func encodeObservation(t *testing.T, k *KlondikeGame) string {
+	buffer := bytes.Buffer{}
+	if err := k.Observation().Write(&buffer); err != nil {
+		t.Fatal(err)
+	}
+	return buffer.String()
+}
+
+func TestKlondikeGame_Observation(t *testing.T) {
+	k := NewSeededKlondikeGame(9)
+	k.Deal()
+	observation := k.Observation()
+	if observation.Stock != 23 || len(observation.Waste) != 1 || observation.Moves != 1 {
+		t.Errorf("Unexpected observation %+v", observation)
+	}
+	if pile := observation.Tableau[6]; len(pile) != 7 || pile[5] != HiddenCard || pile[6] == HiddenCard {
+		t.Errorf("Face-down cards should be hidden and face-up cards shown, got %v", pile)
+	}
+
+	encoded := encodeObservation(t, k)
+	if strings.Contains(encoded, "|") {
+		t.Error("No face-down card should be written out")
+	}
+	hidden := append([]string{}, k.Stock.Cards[0].String())
+	for _, pile := range k.Tableau.Piles {
+		for _, card := range pile {
+			if !card.Revealed {
+				hidden = append(hidden, card.String())
+			}
+		}
+	}
+	for _, cardString := range hidden {
+		if strings.Contains(encoded, `"`+strings.TrimPrefix(cardString, "|")+`"`) {
+			t.Errorf("Face-down card %s shouldn't be observable", cardString)
+		}
+	}
+
+	var decoded Observation
+	if err := json.Unmarshal([]byte(encoded), &decoded); err != nil || decoded.Version != ObservationVersion {
+		t.Errorf("Observations should decode, got %s", err)
+	}
+}
+
+func TestKlondikeGame_Observation_Stable(t *testing.T) {
+	k := parseTestLayout(t, "stock: |5♥ |6♥\n0: |K♠ 9♣\n1:")
+	shuffled := parseTestLayout(t, "stock: |6♥ |5♥\n0: |Q♠ 9♣\n1:")
+	if encodeObservation(t, k) != encodeObservation(t, shuffled) {
+		t.Error("Games that look the same should encode to the same observation")
+	}
+	if !strings.Contains(encodeObservation(t, k), `"tableau": [
+    [
+      "#",
+      "9♣"
+    ],
+    []`) {
+		t.Errorf("Empty piles should encode as [], got %s", encodeObservation(t, k))
+	}
+}
@@ -0,0 +1,296 @@
+package solitaire
+
+import (
+	"fmt"
+	"github.com/jamesboehmer/gopatience/pkg/cards"
+	"github.com/jamesboehmer/gopatience/pkg/cards/pip"
+	"github.com/jamesboehmer/gopatience/pkg/cards/suit"
+)
+
+const (
+	positionPiles   = 7
+	positionPileMax = 19 // six face-down cards under a run from king to ace
+)
+
+// A Position is a klondike position packed into plain arrays, for searching through millions of them without
+// allocating.  Each card is a byte numbering it in deck order, suit.All by pip.All.  Copying a Position copies the
+// whole position, so a search can try a move on a copy and throw it away instead of undoing it, and Positions are
+// comparable, so they can be map keys.  A Position only holds the cards: there's no score, history or options.
+type Position struct {
+	Stock      [52]byte // from the bottom up, so the last card is dealt next
+	StockLen   uint8
+	Waste      [52]byte // from the bottom up
+	WasteLen   uint8
+	Foundation [4]uint8 // the height of each suit's pile, in suit.All order
+	Piles      [positionPiles][positionPileMax]byte
+	PileLen    [positionPiles]uint8
+	Hidden     [positionPiles]uint8 // how many cards at the bottom of each pile are face down
+}
+
+var packedRedSuits = func() (red [4]bool) {
+	for suitNum, s := range suit.All {
+		red[suitNum] = s.Color() == suit.Red
+	}
+	return red
+}()
+
+func packedRank(card byte) int {
+	return int(card%13) + 1
+}
+
+func packedSuit(card byte) int {
+	return int(card / 13)
+}
+
+func packedRed(card byte) bool {
+	return packedRedSuits[card/13]
+}
+
+func unpackCard(card byte) cards.Card {
+	return cards.Card{Pip: pip.All[card%13], Suit: suit.All[card/13]}
+}
+
+// packCard numbers a card that Validate has already checked is a klondike card.
+func packCard(card cards.Card) byte {
+	card.Revealed = false
+	return byte(deckIndex[card])
+}
+
+func suitIndex(foundationSuit suit.Suit) int {
+	for i, s := range suit.All {
+		if s == foundationSuit {
+			return i
+		}
+	}
+	return -1
+}
+
+// NewPosition packs the game's cards into a Position.  The game must pass Validate and have seven tableau piles with
+// no more than six face-down cards in any of them, as in any game dealt by the rules.
+func NewPosition(k *KlondikeGame) (Position, error) {
+	var p Position
+	if err := k.Validate(); err != nil {
+		return p, err
+	}
+	if len(k.Tableau.Piles) != positionPiles {
+		return p, fmt.Errorf("positions have %d tableau piles, not %d", positionPiles, len(k.Tableau.Piles))
+	}
+	for cardNum := range k.Stock.Cards {
+		p.Stock[cardNum] = packCard(k.Stock.Cards[len(k.Stock.Cards)-1-cardNum])
+	}
+	p.StockLen = uint8(len(k.Stock.Cards))
+	for cardNum, card := range k.Waste {
+		p.Waste[cardNum] = packCard(card)
+	}
+	p.WasteLen = uint8(len(k.Waste))
+	for suitNum, foundationSuit := range suit.All {
+		p.Foundation[suitNum] = uint8(len(k.Foundation.Piles[foundationSuit]))
+	}
+	for pileNum, pile := range k.Tableau.Piles {
+		for _, card := range pile {
+			if !card.Revealed {
+				p.Hidden[pileNum]++
+			}
+		}
+		if p.Hidden[pileNum] > positionPileMax-13 {
+			return p, fmt.Errorf("tableau %d has more than %d face-down cards", pileNum, positionPileMax-13)
+		}
+		for cardNum, card := range pile {
+			p.Piles[pileNum][cardNum] = packCard(*card)
+		}
+		p.PileLen[pileNum] = uint8(len(pile))
+	}
+	return p, nil
+}
+
+// Game unpacks the position into a new game with a score of zero and nothing to undo.
+func (p *Position) Game() *KlondikeGame {
+	game := new(KlondikeGame)
+	game.Stock = cards.Deck{NumDecks: 1, IsShuffled: true, Cards: make([]cards.Card, 0, p.StockLen)}
+	for cardNum := int(p.StockLen) - 1; cardNum >= 0; cardNum-- {
+		game.Stock.Cards = append(game.Stock.Cards, unpackCard(p.Stock[cardNum]))
+	}
+	game.Waste = make([]cards.Card, 0, p.WasteLen)
+	for _, packed := range p.Waste[:p.WasteLen] {
+		card := unpackCard(packed)
+		game.Waste = append(game.Waste, *card.Reveal())
+	}
+	game.Foundation = *NewFoundation(klondikeSuits)
+	for suitNum, height := range p.Foundation {
+		for _, cardPip := range pip.All[:height] {
+			game.Foundation.Piles[suit.All[suitNum]] = append(game.Foundation.Piles[suit.All[suitNum]],
+				cards.Card{Pip: cardPip, Suit: suit.All[suitNum], Revealed: true})
+		}
+	}
+	game.Tableau = *NewTableau(positionPiles, nil)
+	for pileNum := range p.Piles {
+		game.Tableau.Piles[pileNum] = make([]*cards.Card, 0, int(p.PileLen[pileNum])+13)
+		for cardNum, packed := range p.Piles[pileNum][:p.PileLen[pileNum]] {
+			card := unpackCard(packed)
+			card.Revealed = cardNum >= int(p.Hidden[pileNum])
+			game.Tableau.Piles[pileNum] = append(game.Tableau.Piles[pileNum], &card)
+		}
+	}
+	game.Rehash()
+	return game
+}
+
+// IsSolved reports whether every card is on the foundation.
+func (p *Position) IsSolved() bool {
+	return p.Foundation == [4]uint8{13, 13, 13, 13}
+}
+
+// fitsFoundation reports whether the card can go on its suit's foundation pile.
+func (p *Position) fitsFoundation(card byte) bool {
+	return int(p.Foundation[packedSuit(card)]) == packedRank(card)-1
+}
+
+// fitsTableau reports whether the card can go on the pile.
+func (p *Position) fitsTableau(card byte, pileNum int) bool {
+	height := p.PileLen[pileNum]
+	if height == 0 {
+		return packedRank(card) == 13
+	}
+	top := p.Piles[pileNum][height-1]
+	return packedRed(card) != packedRed(top) && packedRank(card)+1 == packedRank(top)
+}
+
+// IsLegal reports whether the move is legal in the position, by the same rules as KlondikeGame.IsLegal.
+func (p *Position) IsLegal(move Move) bool {
+	validPile := func(pileNum int) bool { return pileNum >= 0 && pileNum < positionPiles }
+	switch move.Type {
+	case MoveDeal:
+		return p.StockLen+p.WasteLen > 0
+	case MoveWasteFoundation:
+		return p.WasteLen > 0 && p.fitsFoundation(p.Waste[p.WasteLen-1])
+	case MoveWasteTableau:
+		return p.WasteLen > 0 && validPile(move.ToPile) && p.fitsTableau(p.Waste[p.WasteLen-1], move.ToPile)
+	case MoveTableauFoundation:
+		return validPile(move.FromPile) && move.CardNum == int(p.PileLen[move.FromPile])-1 &&
+			move.CardNum >= int(p.Hidden[move.FromPile]) && p.fitsFoundation(p.Piles[move.FromPile][move.CardNum])
+	case MoveTableauTableau:
+		return validPile(move.FromPile) && validPile(move.ToPile) && move.FromPile != move.ToPile &&
+			move.CardNum >= int(p.Hidden[move.FromPile]) && move.CardNum < int(p.PileLen[move.FromPile]) &&
+			p.fitsTableau(p.Piles[move.FromPile][move.CardNum], move.ToPile)
+	case MoveFoundationTableau:
+		suitNum := suitIndex(move.Suit)
+		if suitNum < 0 || p.Foundation[suitNum] == 0 || !validPile(move.ToPile) {
+			return false
+		}
+		return p.fitsTableau(byte(suitNum*13)+p.Foundation[suitNum]-1, move.ToPile)
+	}
+	return false
+}
+
+// LegalMoves appends every legal move to moves and returns it, in the same order as KlondikeGame.LegalMoves.  Passing
+// a slice with room to spare, like moves[:0] from the last call, keeps it from allocating.
+func (p *Position) LegalMoves(moves []Move) []Move {
+	for pileNum := 0; pileNum < positionPiles; pileNum++ {
+		move := Move{Type: MoveTableauFoundation, FromPile: pileNum, CardNum: int(p.PileLen[pileNum]) - 1}
+		if p.IsLegal(move) {
+			moves = append(moves, move)
+		}
+	}
+	if p.IsLegal(Move{Type: MoveWasteFoundation}) {
+		moves = append(moves, Move{Type: MoveWasteFoundation})
+	}
+	for pileNum := 0; pileNum < positionPiles; pileNum++ {
+		for cardNum := int(p.Hidden[pileNum]); cardNum < int(p.PileLen[pileNum]); cardNum++ {
+			for destination := 0; destination < positionPiles; destination++ {
+				if destination != pileNum && p.fitsTableau(p.Piles[pileNum][cardNum], destination) {
+					moves = append(moves, Move{
+						Type: MoveTableauTableau, FromPile: pileNum, CardNum: cardNum, ToPile: destination,
+					})
+				}
+			}
+		}
+	}
+	if p.WasteLen > 0 {
+		for destination := 0; destination < positionPiles; destination++ {
+			if p.fitsTableau(p.Waste[p.WasteLen-1], destination) {
+				moves = append(moves, Move{Type: MoveWasteTableau, ToPile: destination})
+			}
+		}
+	}
+	for _, foundationSuit := range klondikeSuits {
+		for destination := 0; destination < positionPiles; destination++ {
+			move := Move{Type: MoveFoundationTableau, Suit: foundationSuit, ToPile: destination}
+			if p.IsLegal(move) {
+				moves = append(moves, move)
+			}
+		}
+	}
+	if p.IsLegal(Move{Type: MoveDeal}) {
+		moves = append(moves, Move{Type: MoveDeal})
+	}
+	return moves
+}
+
+// Apply makes the move, turning up the next card of a tableau pile left with a face-down top card, or returns an error
+// and leaves the position untouched if the move is illegal.
+func (p *Position) Apply(move Move) error {
+	if !p.IsLegal(move) {
+		return ErrIllegalMove
+	}
+	switch move.Type {
+	case MoveDeal:
+		if p.StockLen == 0 {
+			for cardNum := uint8(0); cardNum < p.WasteLen; cardNum++ {
+				p.Stock[cardNum] = p.Waste[p.WasteLen-1-cardNum]
+				p.Waste[p.WasteLen-1-cardNum] = 0
+			}
+			p.StockLen, p.WasteLen = p.WasteLen, 0
+		}
+		p.Waste[p.WasteLen] = p.popStock()
+		p.WasteLen++
+	case MoveWasteFoundation:
+		p.Foundation[packedSuit(p.popWaste())]++
+	case MoveWasteTableau:
+		p.push(move.ToPile, p.popWaste())
+	case MoveTableauFoundation:
+		p.Foundation[packedSuit(p.Piles[move.FromPile][move.CardNum])]++
+		p.cut(move.FromPile, move.CardNum)
+	case MoveTableauTableau:
+		for _, card := range p.Piles[move.FromPile][move.CardNum:p.PileLen[move.FromPile]] {
+			p.push(move.ToPile, card)
+		}
+		p.cut(move.FromPile, move.CardNum)
+	case MoveFoundationTableau:
+		suitNum := suitIndex(move.Suit)
+		p.Foundation[suitNum]--
+		p.push(move.ToPile, byte(suitNum*13)+p.Foundation[suitNum])
+	}
+	return nil
+}
+
+// The unused ends of the arrays are kept zero so that equal positions compare equal.
+
+func (p *Position) popStock() byte {
+	p.StockLen--
+	card := p.Stock[p.StockLen]
+	p.Stock[p.StockLen] = 0
+	return card
+}
+
+func (p *Position) popWaste() byte {
+	p.WasteLen--
+	card := p.Waste[p.WasteLen]
+	p.Waste[p.WasteLen] = 0
+	return card
+}
+
+func (p *Position) push(pileNum int, card byte) {
+	p.Piles[pileNum][p.PileLen[pileNum]] = card
+	p.PileLen[pileNum]++
+}
+
+// cut takes the pile down to cardNum cards, and turns up its top card if that leaves it face down.
+func (p *Position) cut(pileNum int, cardNum int) {
+	for i := cardNum; i < int(p.PileLen[pileNum]); i++ {
+		p.Piles[pileNum][i] = 0
+	}
+	p.PileLen[pileNum] = uint8(cardNum)
+	if p.Hidden[pileNum] > 0 && p.Hidden[pileNum] == p.PileLen[pileNum] {
+		p.Hidden[pileNum]--
+	}
+}
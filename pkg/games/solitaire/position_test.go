@@ -0,0 +1,145 @@
+package solitaire
+
+import (
+	"math/rand"
+	"testing"
+)
+
+func TestNewPosition(t *testing.T) {
+	k := NewSeededKlondikeGame(5)
+	p, err := NewPosition(k)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if p.StockLen != 24 || p.WasteLen != 0 || p.PileLen != [7]uint8{1, 2, 3, 4, 5, 6, 7} ||
+		p.Hidden != [7]uint8{0, 1, 2, 3, 4, 5, 6} {
+		t.Errorf("Unexpected position for a new deal: %+v", p)
+	}
+	if !p.Game().Equal(k, HashOptions{}) {
+		t.Error("Unpacking a position should give back the same game")
+	}
+
+	k.Tableau.Piles[0][0].Conceal()
+	if _, err := NewPosition(k); err == nil {
+		t.Error("Invalid games shouldn't pack into a position")
+	}
+}
+
+func TestPosition_Apply(t *testing.T) {
+	for seed := int64(1); seed <= 20; seed++ {
+		k := NewSeededKlondikeGame(seed)
+		p, _ := NewPosition(k)
+		random := rand.New(rand.NewSource(seed))
+		var moves []Move
+		for i := 0; i < 200; i++ {
+			expected := k.LegalMoves()
+			moves = p.LegalMoves(moves[:0])
+			if len(moves) != len(expected) {
+				t.Fatalf("Seed %d move %d: expected moves %v, got %v", seed, i, expected, moves)
+			}
+			for moveNum := range moves {
+				if moves[moveNum] != expected[moveNum] {
+					t.Fatalf("Seed %d move %d: expected moves %v, got %v", seed, i, expected, moves)
+				}
+			}
+			if len(moves) == 0 {
+				break
+			}
+			move := moves[random.Intn(len(moves))]
+			if err := p.Apply(move); err != nil {
+				t.Fatalf("Seed %d: %s should be legal, got %s", seed, move, err)
+			}
+			k.Apply(move)
+			if packed, _ := NewPosition(k); packed != p {
+				t.Fatalf("Seed %d: %s should leave the same position in both", seed, move)
+			}
+		}
+	}
+
+	p, _ := NewPosition(NewSeededKlondikeGame(5))
+	before := p
+	if p.Apply(Move{Type: MoveTableauTableau, FromPile: 6, CardNum: 0, ToPile: 1}) == nil || p != before {
+		t.Error("Illegal moves should be refused without changing the position")
+	}
+}
+
+func TestPosition_Allocations(t *testing.T) {
+	p, _ := NewPosition(NewSeededKlondikeGame(5))
+	moves := make([]Move, 0, 64)
+	allocs := testing.AllocsPerRun(100, func() {
+		moves = p.LegalMoves(moves[:0])
+		next := p
+		next.Apply(moves[len(moves)-1])
+	})
+	if allocs != 0 {
+		t.Errorf("Generating and applying moves shouldn't allocate, got %v allocations", allocs)
+	}
+}
+
+// benchmarkGames are positions from part way through some random games, so there's more than a deal to look at.
+func benchmarkGames() []*KlondikeGame {
+	var games []*KlondikeGame
+	for seed := int64(1); seed <= 16; seed++ {
+		k := NewSeededKlondikeGame(seed)
+		random := rand.New(rand.NewSource(seed))
+		for i := 0; i < 30; i++ {
+			moves := k.LegalMoves()
+			if len(moves) == 0 {
+				break
+			}
+			k.Apply(moves[random.Intn(len(moves))])
+		}
+		games = append(games, k.Clone())
+	}
+	return games
+}
+
+func BenchmarkKlondikeGame_LegalMoves(b *testing.B) {
+	games := benchmarkGames()
+	b.ResetTimer()
+	for i := 0; i < b.N; i++ {
+		games[i%len(games)].LegalMoves()
+	}
+}
+
+func BenchmarkPosition_LegalMoves(b *testing.B) {
+	var positions []Position
+	for _, k := range benchmarkGames() {
+		p, _ := NewPosition(k)
+		positions = append(positions, p)
+	}
+	moves := make([]Move, 0, 64)
+	b.ResetTimer()
+	for i := 0; i < b.N; i++ {
+		moves = positions[i%len(positions)].LegalMoves(moves[:0])
+	}
+}
+
+func BenchmarkKlondikeGame_Apply(b *testing.B) {
+	games := benchmarkGames()
+	var moves [][]Move
+	for _, k := range games {
+		moves = append(moves, k.LegalMoves())
+	}
+	b.ResetTimer()
+	for i := 0; i < b.N; i++ {
+		k, legal := games[i%len(games)], moves[i%len(games)]
+		k.Apply(legal[i%len(legal)])
+		k.Undo()
+	}
+}
+
+func BenchmarkPosition_Apply(b *testing.B) {
+	var positions []Position
+	var moves [][]Move
+	for _, k := range benchmarkGames() {
+		p, _ := NewPosition(k)
+		positions = append(positions, p)
+		moves = append(moves, p.LegalMoves(nil))
+	}
+	b.ResetTimer()
+	for i := 0; i < b.N; i++ {
+		next, legal := positions[i%len(positions)], moves[i%len(positions)]
+		next.Apply(legal[i%len(legal)])
+	}
+}
@@ -0,0 +1,78 @@
+package solitaire
+
+import (
+	"errors"
+	"fmt"
+	"github.com/jamesboehmer/gopatience/pkg/cards"
+	"strings"
+)
+
+// A Rejection explains why a card doesn't fit on a pile, e.g. "8♠ on 9♠: same color".  Tableau.Put, Foundation.Put
+// and KlondikeGame.Apply return one when the cards are fine to move but don't fit where they were sent.
+type Rejection struct {
+	Card    cards.Card
+	Pile    string      // where the card was sent, e.g. "tableau 3" or "foundation"
+	Target  *cards.Card // the card it would have gone on, or nil if the pile is empty
+	Reasons []string
+}
+
+func (r *Rejection) Error() string {
+	card := r.Card
+	card.Revealed = true
+	on := r.Pile
+	if r.Target != nil {
+		target := *r.Target
+		target.Revealed = true
+		on = target.String()
+	}
+	return fmt.Sprintf("%s on %s: %s", &card, on, strings.Join(r.Reasons, ", "))
+}
+
+// Is makes every Rejection an ErrNoFit.
+func (r *Rejection) Is(target error) bool {
+	return target == ErrNoFit
+}
+
+// A MoveError says a selected card didn't fit anywhere it was tried, with the Rejection from each place.
+type MoveError struct {
+	Card       cards.Card
+	Rejections []*Rejection
+}
+
+func (e *MoveError) Error() string {
+	card := e.Card
+	card.Revealed = true
+	reasons := make([]string, 0, len(e.Rejections))
+	for _, rejection := range e.Rejections {
+		reasons = append(reasons, rejection.Error())
+	}
+	return fmt.Sprintf("no fit for %s: %s", &card, strings.Join(reasons, "; "))
+}
+
+// Is makes every MoveError an ErrNoFit.
+func (e *MoveError) Is(target error) bool {
+	return target == ErrNoFit
+}
+
+// tryMoves applies the move the game's destination policy likes best out of those that are legal.  If none are, it
+// returns a *MoveError collecting why card didn't fit, or the last error if none of them were about fitting.
+func (k *KlondikeGame) tryMoves(card cards.Card, moves []Move) error {
+	moveError := &MoveError{Card: card}
+	var other error
+	for _, move := range k.orderMoves(moves) {
+		err := k.Apply(move)
+		if err == nil {
+			return nil
+		}
+		var rejection *Rejection
+		if errors.As(err, &rejection) {
+			moveError.Rejections = append(moveError.Rejections, rejection)
+		} else {
+			other = err
+		}
+	}
+	if len(moveError.Rejections) == 0 && other != nil {
+		return other
+	}
+	return moveError
+}
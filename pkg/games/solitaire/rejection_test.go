@@ -0,0 +1,74 @@
+package solitaire
+
+import (
+	"errors"
+	"github.com/jamesboehmer/gopatience/pkg/cards"
+	"github.com/jamesboehmer/gopatience/pkg/cards/suit"
+	"testing"
+)
+
+func TestTableau_Put_Rejection(t *testing.T) {
+	k := parseTestLayout(t, "0: 9♠\n1:\n2: J♥\n3:\n4:\n5:\n6:")
+	for _, test := range []struct {
+		card     string
+		pileNum  int
+		expected string
+	}{
+		{"8♠", 0, "8♠ on 9♠: same color"},
+		{"7♥", 0, "7♥ on 9♠: not one rank lower"},
+		{"7♣", 0, "7♣ on 9♠: same color, not one rank lower"},
+		{"Q♥", 1, "Q♥ on tableau 1: only kings on empty piles"},
+	} {
+		card, _ := cards.ParseCard(test.card)
+		err := k.Tableau.Put([]*cards.Card{card}, test.pileNum)
+		var rejection *Rejection
+		if !errors.As(err, &rejection) || err.Error() != test.expected {
+			t.Errorf("Expected %q, got %v", test.expected, err)
+		}
+	}
+}
+
+func TestFoundation_Put_Rejection(t *testing.T) {
+	f := NewFoundation(klondikeSuits)
+	f.Put(cards.Card{Pip: "A", Suit: suit.Hearts, Revealed: true})
+	for card, expected := range map[cards.Card]string{
+		{Pip: "3", Suit: suit.Hearts, Revealed: true}: "3♥ on A♥: not one rank higher",
+		{Pip: "2", Suit: suit.Spades, Revealed: true}: "2♠ on foundation: only aces on empty piles",
+	} {
+		err := f.Put(card)
+		var rejection *Rejection
+		if !errors.As(err, &rejection) || err.Error() != expected {
+			t.Errorf("Expected %q, got %v", expected, err)
+		}
+	}
+}
+
+func TestKlondikeGame_SelectTableau_MoveError(t *testing.T) {
+	k := parseTestLayout(t, "0: 8♠\n1: 9♠\n2: Q♥\n3:\n4:\n5:\n6:")
+	err := k.SelectTableau(0)
+	var moveError *MoveError
+	if !errors.As(err, &moveError) {
+		t.Fatalf("Expected a *MoveError, got %v", err)
+	}
+	expected := []string{
+		"8♠ on foundation: only aces on empty piles",
+		"8♠ on 9♠: same color",
+		"8♠ on Q♥: not one rank lower",
+		"8♠ on tableau 3: only kings on empty piles",
+	}
+	if len(moveError.Rejections) != len(k.Tableau.Piles) {
+		t.Fatalf("Expected the foundation and every other pile to be tried, got %s", err)
+	}
+	for i, reason := range expected {
+		if moveError.Rejections[i].Error() != reason {
+			t.Errorf("Expected %q, got %q", reason, moveError.Rejections[i])
+		}
+	}
+
+	// an explicit destination only tries that pile
+	err = k.SelectTableau(0, 0, 2)
+	expectedError := "no fit for 8♠: 8♠ on Q♥: not one rank lower"
+	if !errors.As(err, &moveError) || len(moveError.Rejections) != 1 || err.Error() != expectedError {
+		t.Errorf("Unexpected error %v", err)
+	}
+}
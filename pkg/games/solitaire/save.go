@@ -0,0 +1,282 @@
+package solitaire
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"github.com/jamesboehmer/gopatience/pkg/cards"
+	"github.com/jamesboehmer/gopatience/pkg/cards/suit"
+	"github.com/jamesboehmer/gopatience/pkg/games/util"
+	"io"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"time"
+)
+
+// KlondikeSaveVersion is the save format version written by Save.  Bump it whenever klondikeSave changes, and add a
+// migration from the previous version to saveMigrations.
+const KlondikeSaveVersion = 6
+
+// KlondikeVariant marks klondike saves, so they can be told apart from saves of other games.
+const KlondikeVariant = "klondike"
+
+// klondikeSave is the on-disk form of a game.  Cards are written the way cards.ParseCard reads them, with a "|" prefix
+// for face-down cards.  The undo history isn't saved.
+type klondikeSave struct {
+	Version    int                 `json:"version"`
+	Variant    string              `json:"variant"`
+	Saved      time.Time           `json:"saved"`
+	Seed       int64               `json:"seed"`
+	Deal       string              `json:"deal,omitempty"`
+	Score      int                 `json:"score"`
+	Options    klondikeOptions     `json:"options"`
+	Stock      []string            `json:"stock"`
+	Waste      []string            `json:"waste"`
+	Foundation map[string][]string `json:"foundation"`
+	Tableau    [][]string          `json:"tableau"`
+	History    []Move              `json:"history"`
+	Unranked   bool                `json:"unranked"`
+	Signature  string              `json:"signature,omitempty"`
+}
+
+type klondikeOptions struct {
+	AutoPlay     bool   `json:"autoplay"`
+	Destinations string `json:"destinations,omitempty"`
+}
+
+// Save writes the game to w in the current save format.
+func (k *KlondikeGame) Save(w io.Writer) error {
+	data, err := k.encode(time.Now().UTC())
+	if err != nil {
+		return err
+	}
+	_, err = w.Write(data)
+	return err
+}
+
+// encode returns the game in the current save format, stamped as saved at the given time.
+func (k *KlondikeGame) encode(saved time.Time) ([]byte, error) {
+	save := klondikeSave{
+		Version:    KlondikeSaveVersion,
+		Variant:    KlondikeVariant,
+		Saved:      saved,
+		Seed:       k.Seed,
+		Score:      k.Score,
+		Options:    klondikeOptions{AutoPlay: k.AutoPlay, Destinations: k.Destinations.String()},
+		Stock:      []string{},
+		Waste:      []string{},
+		Foundation: make(map[string][]string, len(k.Foundation.Piles)),
+		Tableau:    make([][]string, len(k.Tableau.Piles)),
+		History:    append([]Move{}, k.History...),
+		Unranked:   k.Unranked,
+	}
+	if code, err := k.DealCode(); err == nil {
+		save.Deal = code
+	}
+	for _, card := range k.Stock.Cards {
+		save.Stock = append(save.Stock, card.String())
+	}
+	for _, card := range k.Waste {
+		save.Waste = append(save.Waste, card.String())
+	}
+	for suit, pile := range k.Foundation.Piles {
+		save.Foundation[string(suit)] = []string{}
+		for _, card := range pile {
+			save.Foundation[string(suit)] = append(save.Foundation[string(suit)], card.String())
+		}
+	}
+	for pileNum, pile := range k.Tableau.Piles {
+		save.Tableau[pileNum] = []string{}
+		for _, card := range pile {
+			save.Tableau[pileNum] = append(save.Tableau[pileNum], card.String())
+		}
+	}
+	if err := save.sign(); err != nil {
+		return nil, err
+	}
+	data, err := json.MarshalIndent(save, "", "  ")
+	if err != nil {
+		return nil, err
+	}
+	return append(data, '\n'), nil
+}
+
+// LoadKlondikeGame reads a game written by Save, upgrading saves from older versions as it goes.  The loaded game keeps
+// its move history, but starts with nothing to undo.
+func LoadKlondikeGame(r io.Reader) (*KlondikeGame, error) {
+	game, _, err := loadKlondikeGame(r)
+	return game, err
+}
+
+// loadKlondikeGame loads a game and also returns the save version it was read from.
+func loadKlondikeGame(r io.Reader) (*KlondikeGame, int, error) {
+	save, version, err := readSave(r)
+	if err != nil {
+		return nil, 0, err
+	}
+	game, err := save.game()
+	return game, version, err
+}
+
+// readSave decodes a save in the current format, upgrading it if needed, and returns the version it was read from.
+func readSave(r io.Reader) (*klondikeSave, int, error) {
+	data, err := ioutil.ReadAll(r)
+	if err != nil {
+		return nil, 0, err
+	}
+	data, version, err := migrateSave(data)
+	if err != nil {
+		return nil, 0, err
+	}
+	save := new(klondikeSave)
+	if err := json.Unmarshal(data, save); err != nil {
+		return nil, 0, fmt.Errorf("invalid save: %w", err)
+	}
+	if save.Variant != KlondikeVariant {
+		return nil, 0, fmt.Errorf("invalid save: %q is not a klondike game", save.Variant)
+	}
+	return save, version, nil
+}
+
+// DefaultKlondikeSavePath returns ~/.gopatience/klondike.save, where games are saved when no file is given.
+func DefaultKlondikeSavePath() (string, error) {
+	home, err := os.UserHomeDir()
+	if err != nil {
+		return "", err
+	}
+	return filepath.Join(home, ".gopatience", "klondike.save"), nil
+}
+
+// DefaultSaveBackups is how many older copies of a save file SaveFile keeps next to it.
+const DefaultSaveBackups = 3
+
+// SaveFile writes the game to path, creating its directory if needed, and keeps DefaultSaveBackups older copies.
+func (k *KlondikeGame) SaveFile(path string) error {
+	return k.SaveFileBackups(path, DefaultSaveBackups)
+}
+
+// SaveFileBackups writes the game to path after moving the previous save to path.1, path.1 to path.2 and so on, up to
+// backups copies.  The old file is only replaced once the new one has been written completely.
+func (k *KlondikeGame) SaveFileBackups(path string, backups int) error {
+	if err := os.MkdirAll(filepath.Dir(path), 0755); err != nil {
+		return err
+	}
+	buffer := bytes.Buffer{}
+	if err := k.Save(&buffer); err != nil {
+		return err
+	}
+	if err := util.RotateBackups(path, backups); err != nil {
+		return err
+	}
+	return util.WriteFileAtomic(path, buffer.Bytes(), 0644)
+}
+
+// LoadKlondikeFile reads a game saved with SaveFile, falling back to its newest readable backup if the save itself is
+// missing or corrupt.
+func LoadKlondikeFile(path string) (*KlondikeGame, error) {
+	game, _, err := RecoverKlondikeFile(path, DefaultSaveBackups)
+	return game, err
+}
+
+// RecoverKlondikeFile loads path, or else the newest of its backups that can be read, and returns the file it used.
+// If nothing can be loaded, the error is the one from path itself.
+func RecoverKlondikeFile(path string, backups int) (*KlondikeGame, string, error) {
+	game, err := readKlondikeFile(path)
+	if err == nil {
+		return game, path, nil
+	}
+	for i := 1; i <= backups; i++ {
+		if game, backupErr := readKlondikeFile(util.BackupPath(path, i)); backupErr == nil {
+			return game, util.BackupPath(path, i), nil
+		}
+	}
+	return nil, "", err
+}
+
+func readKlondikeFile(path string) (*KlondikeGame, error) {
+	file, err := os.Open(path)
+	if err != nil {
+		return nil, err
+	}
+	defer file.Close()
+	return LoadKlondikeGame(file)
+}
+
+func (save *klondikeSave) game() (*KlondikeGame, error) {
+	game := new(KlondikeGame)
+	game.Seed = save.Seed
+	game.Score = save.Score
+	game.AutoPlay = save.Options.AutoPlay
+	if save.Options.Destinations != "" {
+		destinations, err := ParseDestinationPolicy(save.Options.Destinations)
+		if err != nil {
+			return nil, fmt.Errorf("invalid save: %w", err)
+		}
+		game.Destinations = destinations
+	}
+	game.History = save.History
+	game.Unranked = save.Unranked || !save.verify()
+	if save.Deal != "" {
+		deck, _, err := decodeDealCode(save.Deal)
+		if err != nil {
+			return nil, fmt.Errorf("invalid save: %w", err)
+		}
+		game.order = deck.Cards
+	}
+	game.Stock = cards.Deck{NumDecks: 1, IsShuffled: true, Cards: []cards.Card{}}
+	game.Waste = []cards.Card{}
+	game.Foundation = *NewFoundation(klondikeSuits)
+	game.Tableau.Piles = make([][]*cards.Card, len(save.Tableau))
+
+	for _, cardString := range save.Stock {
+		card, err := parseSavedCard(cardString)
+		if err != nil {
+			return nil, err
+		}
+		game.Stock.Cards = append(game.Stock.Cards, *card)
+	}
+	for _, cardString := range save.Waste {
+		card, err := parseSavedCard(cardString)
+		if err != nil {
+			return nil, err
+		}
+		game.Waste = append(game.Waste, *card)
+	}
+	for suitString, pile := range save.Foundation {
+		suit, found := suit.Suits[suitString]
+		if !found {
+			return nil, fmt.Errorf("invalid save: unknown foundation suit %q", suitString)
+		}
+		for _, cardString := range pile {
+			card, err := parseSavedCard(cardString)
+			if err != nil {
+				return nil, err
+			}
+			game.Foundation.Piles[suit] = append(game.Foundation.Piles[suit], *card)
+		}
+	}
+	for pileNum, pile := range save.Tableau {
+		game.Tableau.Piles[pileNum] = make([]*cards.Card, 0, len(pile)+13)
+		for _, cardString := range pile {
+			card, err := parseSavedCard(cardString)
+			if err != nil {
+				return nil, err
+			}
+			game.Tableau.Piles[pileNum] = append(game.Tableau.Piles[pileNum], card)
+		}
+	}
+	if err := game.Validate(); err != nil {
+		return nil, fmt.Errorf("invalid save: %w", err)
+	}
+	game.Rehash()
+	return game, nil
+}
+
+func parseSavedCard(cardString string) (*cards.Card, error) {
+	card, err := cards.ParseCard(cardString)
+	if err != nil {
+		return nil, fmt.Errorf("invalid save: card %q: %w", cardString, err)
+	}
+	return card, nil
+}
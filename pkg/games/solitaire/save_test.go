@@ -0,0 +1,142 @@
+package solitaire
+
+import (
+	"bytes"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+)
+
+func TestKlondikeGame_SaveLoad(t *testing.T) {
+	k := NewSeededKlondikeGame(7)
+	k.AutoPlay = true
+	k.Deal()
+	k.Deal()
+	k.Score = 25
+	buffer := bytes.Buffer{}
+	if err := k.Save(&buffer); err != nil {
+		t.Fatal("Save should not return an error")
+	}
+	loaded, err := LoadKlondikeGame(&buffer)
+	if err != nil {
+		t.Fatalf("Load should not return an error, got %s", err)
+	}
+	if !loaded.Equal(k, HashOptions{}) {
+		t.Error("Loaded game should have the same cards as the saved game")
+	}
+	if len(loaded.History) != 2 || loaded.History[0] != (Move{Type: MoveDeal}) {
+		t.Error("Loaded game should have the same history as the saved game")
+	}
+	if loaded.Score != 25 || loaded.Seed != 7 || !loaded.AutoPlay {
+		t.Error("Loaded game should have the same score, seed and options as the saved game")
+	}
+	for pileNum, pile := range k.Tableau.Piles {
+		for cardNum, card := range pile {
+			if loaded.Tableau.Piles[pileNum][cardNum].Revealed != card.Revealed {
+				t.Errorf("Tableau card %d %d should have kept its revealed flag", pileNum, cardNum)
+			}
+		}
+	}
+	if err := loaded.Deal(); err != nil {
+		t.Error("Loaded game should be playable")
+	}
+}
+
+func TestLoadKlondikeGame_Version(t *testing.T) {
+	if _, err := LoadKlondikeGame(strings.NewReader(`{"version": 99}`)); err == nil ||
+		err.Error() != "unsupported save version 99, expected 6 or older" {
+		t.Errorf("Newer save versions should be rejected, got %v", err)
+	}
+	if _, err := LoadKlondikeGame(strings.NewReader(`{}`)); err == nil {
+		t.Error("Saves with no version should be rejected")
+	}
+	if _, err := LoadKlondikeGame(strings.NewReader(`{"version": 1, "stock": ["|"]}`)); err == nil {
+		t.Error("Saves with invalid cards should be rejected")
+	}
+}
+
+func TestKlondikeGame_SaveFile(t *testing.T) {
+	dir, err := ioutil.TempDir("", "gopatience")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer os.RemoveAll(dir)
+	path := filepath.Join(dir, "saves", "klondike.save")
+	k := NewSeededKlondikeGame(7)
+	if err := k.SaveFile(path); err != nil {
+		t.Fatalf("SaveFile should create the save directory, got %s", err)
+	}
+	if err := k.SaveFile(path); err != nil {
+		t.Fatalf("SaveFile should replace an existing save, got %s", err)
+	}
+	files, _ := ioutil.ReadDir(filepath.Dir(path))
+	for _, file := range files {
+		if strings.Contains(file.Name(), ".tmp") {
+			t.Errorf("SaveFile should leave no temporary files behind, found %s", file.Name())
+		}
+	}
+	loaded, err := LoadKlondikeFile(path)
+	if err != nil || !loaded.Equal(k, HashOptions{}) {
+		t.Error("LoadKlondikeFile should load the saved game")
+	}
+}
+
+func TestKlondikeGame_SaveFileBackups(t *testing.T) {
+	dir, err := ioutil.TempDir("", "gopatience")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer os.RemoveAll(dir)
+	path := filepath.Join(dir, "klondike.save")
+	k := NewSeededKlondikeGame(7)
+	var keys []*KlondikeGame
+	for i := 0; i < 4; i++ {
+		keys = append(keys, k.Clone())
+		if err := k.SaveFileBackups(path, 2); err != nil {
+			t.Fatal(err)
+		}
+		k.Deal()
+	}
+	for n, key := range map[string]*KlondikeGame{path: keys[3], path + ".1": keys[2], path + ".2": keys[1]} {
+		saved, err := readKlondikeFile(n)
+		if err != nil || !saved.Equal(key, HashOptions{}) {
+			t.Errorf("%s should hold the save from the matching move", filepath.Base(n))
+		}
+	}
+	if _, err := os.Stat(path + ".3"); err == nil {
+		t.Error("Only 2 backups should be kept")
+	}
+}
+
+func TestRecoverKlondikeFile(t *testing.T) {
+	dir, err := ioutil.TempDir("", "gopatience")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer os.RemoveAll(dir)
+	path := filepath.Join(dir, "klondike.save")
+	k := NewSeededKlondikeGame(7)
+	k.SaveFile(path)
+	k.Deal()
+	k.SaveFile(path)
+	ioutil.WriteFile(path, []byte(`{"version": 3, "stock": [`), 0644)
+
+	recovered, from, err := RecoverKlondikeFile(path, DefaultSaveBackups)
+	if err != nil || from != path+".1" {
+		t.Fatalf("A corrupt save should be recovered from its first backup, got %q %v", from, err)
+	}
+	if recovered.Stock.Remaining() != 24 {
+		t.Error("The recovered game should be the one from the backup")
+	}
+	if _, err := LoadKlondikeFile(path); err != nil {
+		t.Error("LoadKlondikeFile should fall back to a backup")
+	}
+
+	os.Remove(path + ".1")
+	if _, _, err := RecoverKlondikeFile(path, DefaultSaveBackups); err == nil ||
+		!strings.HasPrefix(err.Error(), "invalid save") {
+		t.Errorf("With no good backups the save's own error should be returned, got %v", err)
+	}
+}
@@ -0,0 +1,65 @@
+package solitaire
+
+import (
+	"bytes"
+	"strings"
+	"testing"
+)
+
+func signedSave(t *testing.T, k *KlondikeGame) string {
+	buffer := bytes.Buffer{}
+	if err := k.Save(&buffer); err != nil {
+		t.Fatal(err)
+	}
+	return buffer.String()
+}
+
+func TestKlondikeGame_SignedSave(t *testing.T) {
+	SaveSigningKey = []byte("office leaderboard")
+	defer func() { SaveSigningKey = nil }()
+	k := NewSeededKlondikeGame(7)
+	k.Deal()
+	save := signedSave(t, k)
+	if !strings.Contains(save, `"signature"`) {
+		t.Fatal("Saves should be signed when there's a key")
+	}
+
+	loaded, err := LoadKlondikeGame(strings.NewReader(save))
+	if err != nil || loaded.Unranked {
+		t.Errorf("An untouched signed save should load ranked, got %v", err)
+	}
+	for name, tampered := range map[string]string{
+		"edited":   strings.Replace(save, `"score": 0`, `"score": 500`, 1),
+		"unsigned": strings.Replace(save, `"signature"`, `"unsigned"`, 1),
+		"history":  strings.Replace(save, `"history": [`, `"history": [{"type": 1}, `, 1),
+	} {
+		loaded, err := LoadKlondikeGame(strings.NewReader(tampered))
+		if err != nil {
+			t.Fatalf("%s save should still load, got %s", name, err)
+		}
+		if !loaded.Unranked {
+			t.Errorf("%s save should be unranked", name)
+		}
+		// signing it again doesn't make it ranked
+		resaved, _ := LoadKlondikeGame(strings.NewReader(signedSave(t, loaded)))
+		if !resaved.Unranked {
+			t.Errorf("%s save should stay unranked after it's saved again", name)
+		}
+	}
+
+	SaveSigningKey = []byte("someone else's key")
+	if loaded, _ := LoadKlondikeGame(strings.NewReader(save)); !loaded.Unranked {
+		t.Error("A save signed with a different key should be unranked")
+	}
+}
+
+func TestKlondikeGame_UnsignedSave(t *testing.T) {
+	save := signedSave(t, NewSeededKlondikeGame(7))
+	if strings.Contains(save, `"signature"`) {
+		t.Error("Saves should not be signed without a key")
+	}
+	edited := strings.Replace(save, `"score": 0`, `"score": 500`, 1)
+	if loaded, _ := LoadKlondikeGame(strings.NewReader(edited)); loaded.Unranked {
+		t.Error("Signatures should not be checked without a key")
+	}
+}
@@ -0,0 +1,146 @@
+package solitaire
+
+import (
+	"errors"
+	"github.com/jamesboehmer/gopatience/pkg/games/util"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"regexp"
+	"sort"
+	"strings"
+	"time"
+)
+
+const slotExtension = ".save"
+
+var slotName = regexp.MustCompile(`^[A-Za-z0-9_-]+$`)
+
+// SaveSlots keeps named saves in a directory, one file per slot.
+type SaveSlots struct {
+	Dir string
+}
+
+// A SaveSlot describes a saved game without loading it.
+type SaveSlot struct {
+	Name       string
+	Variant    string
+	Score      int
+	Moves      int
+	LastPlayed time.Time
+	Completion int // percentage of the cards on the foundation
+	Unranked   bool
+}
+
+// DefaultSaveSlots returns the slots in ~/.gopatience/saves.
+func DefaultSaveSlots() (*SaveSlots, error) {
+	home, err := os.UserHomeDir()
+	if err != nil {
+		return nil, err
+	}
+	return &SaveSlots{Dir: filepath.Join(home, ".gopatience", "saves")}, nil
+}
+
+// IsSlotName reports whether name can be used as a slot name: letters, digits, dashes and underscores only.
+func IsSlotName(name string) bool {
+	return slotName.MatchString(name)
+}
+
+func (s *SaveSlots) path(name string) (string, error) {
+	if !IsSlotName(name) {
+		return "", errors.New("invalid slot name")
+	}
+	return filepath.Join(s.Dir, name+slotExtension), nil
+}
+
+// Save writes the game to the named slot, replacing whatever was there.
+func (s *SaveSlots) Save(name string, k *KlondikeGame) error {
+	path, err := s.path(name)
+	if err != nil {
+		return err
+	}
+	return k.SaveFile(path)
+}
+
+func (s *SaveSlots) Load(name string) (*KlondikeGame, error) {
+	path, err := s.path(name)
+	if err != nil {
+		return nil, err
+	}
+	if _, err := os.Stat(path); os.IsNotExist(err) {
+		return nil, errors.New("no such slot")
+	}
+	return LoadKlondikeFile(path)
+}
+
+func (s *SaveSlots) Delete(name string) error {
+	path, err := s.path(name)
+	if err != nil {
+		return err
+	}
+	if err := os.Remove(path); os.IsNotExist(err) {
+		return errors.New("no such slot")
+	} else if err != nil {
+		return err
+	}
+	for i := 1; i <= DefaultSaveBackups; i++ {
+		if err := os.Remove(util.BackupPath(path, i)); err != nil && !os.IsNotExist(err) {
+			return err
+		}
+	}
+	return nil
+}
+
+// List describes every readable slot, most recently played first.  Slots that can't be read are left out.
+func (s *SaveSlots) List() ([]SaveSlot, error) {
+	files, err := ioutil.ReadDir(s.Dir)
+	if os.IsNotExist(err) {
+		return nil, nil
+	} else if err != nil {
+		return nil, err
+	}
+	var slots []SaveSlot
+	for _, file := range files {
+		name := strings.TrimSuffix(file.Name(), slotExtension)
+		if file.IsDir() || !strings.HasSuffix(file.Name(), slotExtension) || !IsSlotName(name) {
+			continue
+		}
+		slot, err := s.describe(name, file.ModTime())
+		if err != nil {
+			continue
+		}
+		slots = append(slots, slot)
+	}
+	sort.SliceStable(slots, func(i, j int) bool { return slots[i].LastPlayed.After(slots[j].LastPlayed) })
+	return slots, nil
+}
+
+func (s *SaveSlots) describe(name string, modTime time.Time) (SaveSlot, error) {
+	file, err := os.Open(filepath.Join(s.Dir, name+slotExtension))
+	if err != nil {
+		return SaveSlot{}, err
+	}
+	defer file.Close()
+	save, _, err := readSave(file)
+	if err != nil {
+		return SaveSlot{}, err
+	}
+	slot := SaveSlot{
+		Name:       name,
+		Variant:    save.Variant,
+		Score:      save.Score,
+		Moves:      len(save.History),
+		LastPlayed: save.Saved,
+		Unranked:   save.Unranked || !save.verify(),
+	}
+	if slot.LastPlayed.IsZero() {
+		// saves from before version 3 don't record when they were made
+		slot.LastPlayed = modTime
+	}
+	home := 0
+	for _, pile := range save.Foundation {
+		home += len(pile)
+	}
+	slot.Completion = home * 100 / 52
+	return slot, nil
+}
@@ -0,0 +1,76 @@
+package solitaire
+
+import (
+	"github.com/jamesboehmer/gopatience/pkg/cards"
+	"github.com/jamesboehmer/gopatience/pkg/cards/pip"
+	"github.com/jamesboehmer/gopatience/pkg/cards/suit"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"testing"
+)
+
+func newTestSlots(t *testing.T) *SaveSlots {
+	dir, err := ioutil.TempDir("", "gopatience")
+	if err != nil {
+		t.Fatal(err)
+	}
+	return &SaveSlots{Dir: filepath.Join(dir, "saves")}
+}
+
+func TestSaveSlots(t *testing.T) {
+	slots := newTestSlots(t)
+	defer os.RemoveAll(filepath.Dir(slots.Dir))
+
+	if list, err := slots.List(); err != nil || len(list) != 0 {
+		t.Error("A missing slot directory should have no slots")
+	}
+	first := NewSeededKlondikeGame(1)
+	second := NewSeededKlondikeGame(7)
+	second.Deal()
+	second.Deal()
+	if err := slots.Save("first", first); err != nil {
+		t.Fatalf("Save should not return an error, got %s", err)
+	}
+	if err := slots.Save("second", second); err != nil {
+		t.Fatalf("Save should not return an error, got %s", err)
+	}
+	list, err := slots.List()
+	if err != nil || len(list) != 2 {
+		t.Fatalf("Expected 2 slots, got %d %v", len(list), err)
+	}
+	if list[0].Name != "second" || list[0].Moves != 2 || list[0].Variant != KlondikeVariant || list[0].Unranked {
+		t.Errorf("Most recently played slot should be first, got %+v", list[0])
+	}
+
+	loaded, err := slots.Load("second")
+	if err != nil || !loaded.Equal(second, HashOptions{}) {
+		t.Error("Load should return the game saved in the slot")
+	}
+	if err := slots.Delete("second"); err != nil {
+		t.Error("Delete should not return an error")
+	}
+	if _, err := slots.Load("second"); err == nil || err.Error() != "no such slot" {
+		t.Errorf("Loading a deleted slot should fail, got %v", err)
+	}
+	if err := slots.Delete("second"); err == nil {
+		t.Error("Deleting a missing slot should fail")
+	}
+	if err := slots.Save("../escape", first); err == nil {
+		t.Error("Slot names with path separators should be rejected")
+	}
+}
+
+func TestSaveSlot_Completion(t *testing.T) {
+	slots := newTestSlots(t)
+	defer os.RemoveAll(filepath.Dir(slots.Dir))
+	k := NewSeededKlondikeGame(7)
+	for _, pip := range pip.All {
+		k.Foundation.Piles[suit.Hearts] = append(k.Foundation.Piles[suit.Hearts], cards.Card{Pip: pip, Suit: suit.Hearts})
+	}
+	slots.Save("quarter", k)
+	list, _ := slots.List()
+	if len(list) != 1 || list[0].Completion != 25 {
+		t.Errorf("A quarter of the cards on the foundation should be 25%% complete, got %+v", list)
+	}
+}
@@ -0,0 +1,393 @@
+package solitaire
+
+import (
+	"context"
+	"github.com/jamesboehmer/gopatience/pkg/cards/pip"
+	"sort"
+	"strings"
+	"sync"
+	"sync/atomic"
+	"time"
+)
+
+type SolverResult int
+
+const (
+	SolverUnknown SolverResult = iota
+	SolverSolved
+	SolverUnsolvable
+)
+
+const (
+	DefaultSolverNodes            = 200000
+	DefaultSolverProgressInterval = 250 * time.Millisecond
+)
+
+func (r SolverResult) String() string {
+	switch r {
+	case SolverSolved:
+		return "solved"
+	case SolverUnsolvable:
+		return "unsolvable"
+	}
+	return "unknown"
+}
+
+// A Solver searches for a winning line from a klondike position.  It looks at every card, face down or not, so the
+// search can prove a deal winnable or not.  With Hidden set it only plays through cards a player could have seen: any
+// move that would turn up a face-down tableau card or an unseen stock card ends that line, and a deal it can't finish
+// without one is reported as unknown.  Almost every line of a fresh deal needs one, so Hidden is only much use late in
+// a game, once the stock has been through the waste and most of the tableau is face up.
+//
+// With more than one worker, the top of the search tree is split between goroutines that share one table of visited
+// positions.  If Progress is set, it's called from a single goroutine every ProgressInterval while the search runs,
+// and once more when it finishes.
+type Solver struct {
+	MaxNodes         int
+	Hidden           bool
+	Workers          int
+	Progress         func(SolverProgress)
+	ProgressInterval time.Duration
+}
+
+type SolverProgress struct {
+	Nodes          int
+	Depth          int
+	BestFoundation int
+}
+
+// A Solution is what a search found.  Unseen is set when a Hidden search cut a line short because it would have
+// turned up a card the player hasn't seen, which is usually why a Hidden search ends up unknown.
+type Solution struct {
+	Result SolverResult
+	Moves  []Move
+	Nodes  int
+	Unseen bool
+}
+
+// Script returns the solution as klondike CLI commands, one per line, ready to be piped back into the game.  The
+// search plays every move itself, so the script starts by turning autoplay off, or autoplay would take cards the
+// script means to move later.  A solution with no moves has no script.
+func (s Solution) Script() string {
+	if len(s.Moves) == 0 {
+		return ""
+	}
+	script := strings.Builder{}
+	script.WriteString("autoplay off\n")
+	for _, move := range s.Moves {
+		script.WriteString(move.Command())
+		script.WriteString("\n")
+	}
+	return script.String()
+}
+
+// solverSearch is the state shared by every worker of one search.  Counters and flags are only touched atomically.
+type solverSearch struct {
+	*Solver
+	visited        sync.Map
+	nodes          int64
+	maxDepth       int64
+	bestFoundation int64
+	stopped        int32
+	interrupted    int32
+	exhausted      int32
+	unknown        int32
+	solutionLock   sync.Mutex
+	solution       []Move
+}
+
+// A solverWorker plays one line of the search at a time on its own copy of the game.
+type solverWorker struct {
+	*solverSearch
+	game       *KlondikeGame
+	path       []Move
+	stockKnown bool
+}
+
+func (s *Solver) Solve(k *KlondikeGame) Solution {
+	return s.SolveContext(context.Background(), k)
+}
+
+// SolveContext searches from the game's current position until it finds a win, runs out of positions or nodes, or
+// ctx is done.  The search plays on copies, so k is never changed.
+func (s *Solver) SolveContext(ctx context.Context, k *KlondikeGame) Solution {
+	ctx, cancel := context.WithCancel(ctx)
+	defer cancel()
+	search := &solverSearch{Solver: s}
+	if ctx.Err() != nil {
+		search.stopped = 1
+	}
+	stopping := make(chan bool)
+	go func() {
+		<-ctx.Done()
+		atomic.StoreInt32(&search.stopped, 1)
+		close(stopping)
+	}()
+	reporting := sync.WaitGroup{}
+	if s.Progress != nil {
+		reporting.Add(1)
+		go search.report(stopping, &reporting)
+	}
+
+	root := search.newWorker(k)
+	if s.Workers <= 1 {
+		search.run(root)
+	} else {
+		work := make(chan []Move, 4*s.Workers)
+		go func() {
+			defer close(work)
+			for _, branch := range root.split(4 * s.Workers) {
+				work <- branch
+			}
+		}()
+		workers := sync.WaitGroup{}
+		for i := 0; i < s.Workers; i++ {
+			workers.Add(1)
+			go func() {
+				defer workers.Done()
+				for branch := range work {
+					worker := search.newWorker(k)
+					for _, move := range branch {
+						worker.apply(move)
+					}
+					if search.run(worker) {
+						cancel()
+					}
+				}
+			}()
+		}
+		workers.Wait()
+	}
+	cancel()
+	reporting.Wait()
+
+	solution := Solution{Nodes: int(atomic.LoadInt64(&search.nodes)), Unseen: atomic.LoadInt32(&search.unknown) == 1}
+	if solution.Nodes > search.maxNodes() {
+		solution.Nodes = search.maxNodes()
+	}
+	switch {
+	case search.solution != nil:
+		solution.Result = SolverSolved
+		solution.Moves = search.solution
+	case atomic.LoadInt32(&search.exhausted) == 1 || atomic.LoadInt32(&search.unknown) == 1 ||
+		atomic.LoadInt32(&search.interrupted) == 1:
+		solution.Result = SolverUnknown
+	default:
+		solution.Result = SolverUnsolvable
+	}
+	return solution
+}
+
+func (s *solverSearch) newWorker(k *KlondikeGame) *solverWorker {
+	return &solverWorker{solverSearch: s, game: searchCopy(k), stockKnown: !s.Hidden}
+}
+
+// searchCopy clones a game for searching, where every move has to be undone on its own and the history isn't needed.
+func searchCopy(k *KlondikeGame) *KlondikeGame {
+	game := k.Clone()
+	game.AutoPlay = false
+	game.History = nil
+	return game
+}
+
+// run searches from the worker's position and records its line if it wins.
+func (s *solverSearch) run(worker *solverWorker) bool {
+	if !worker.search() {
+		return false
+	}
+	s.solutionLock.Lock()
+	defer s.solutionLock.Unlock()
+	if s.solution == nil {
+		s.solution = append([]Move{}, worker.path...)
+	}
+	return true
+}
+
+func (s *solverSearch) report(stopping <-chan bool, reporting *sync.WaitGroup) {
+	defer reporting.Done()
+	interval := s.ProgressInterval
+	if interval <= 0 {
+		interval = DefaultSolverProgressInterval
+	}
+	ticker := time.NewTicker(interval)
+	defer ticker.Stop()
+	for {
+		select {
+		case <-ticker.C:
+			s.Progress(s.progress())
+		case <-stopping:
+			s.Progress(s.progress())
+			return
+		}
+	}
+}
+
+func (s *solverSearch) progress() SolverProgress {
+	nodes := int(atomic.LoadInt64(&s.nodes))
+	if nodes > s.maxNodes() {
+		nodes = s.maxNodes()
+	}
+	return SolverProgress{
+		Nodes:          nodes,
+		Depth:          int(atomic.LoadInt64(&s.maxDepth)),
+		BestFoundation: int(atomic.LoadInt64(&s.bestFoundation)),
+	}
+}
+
+func (s *solverSearch) maxNodes() int {
+	if s.MaxNodes > 0 {
+		return s.MaxNodes
+	}
+	return DefaultSolverNodes
+}
+
+// storeMax raises *addr to value unless it's already at least that high.
+func storeMax(addr *int64, value int64) {
+	for {
+		current := atomic.LoadInt64(addr)
+		if value <= current || atomic.CompareAndSwapInt64(addr, current, value) {
+			return
+		}
+	}
+}
+
+func (w *solverWorker) apply(move Move) {
+	if move.Type == MoveDeal && w.game.Stock.Remaining() == 0 {
+		// recycling the waste means every stock card has been seen
+		w.stockKnown = true
+	}
+	w.game.Apply(move)
+	w.path = append(w.path, move)
+}
+
+// visit counts a node against the budget and reports whether the search should go on from it.
+func (w *solverWorker) visit() bool {
+	if atomic.LoadInt32(&w.stopped) == 1 {
+		atomic.StoreInt32(&w.interrupted, 1)
+		return false
+	}
+	if atomic.AddInt64(&w.nodes, 1) > int64(w.maxNodes()) {
+		atomic.StoreInt32(&w.exhausted, 1)
+		return false
+	}
+	storeMax(&w.maxDepth, int64(len(w.path)))
+	foundation := 0
+	for _, pile := range w.game.Foundation.Piles {
+		foundation += len(pile)
+	}
+	storeMax(&w.bestFoundation, int64(foundation))
+	return true
+}
+
+func (w *solverWorker) search() bool {
+	if !w.visit() {
+		return false
+	}
+	if w.game.IsSolved() {
+		return true
+	}
+	key := visitKey{hash: w.game.Hash(HashOptions{IgnorePileOrder: true}), stockKnown: w.stockKnown}
+	if _, seen := w.visited.LoadOrStore(key, true); seen {
+		return false
+	}
+
+	for _, move := range w.candidateMoves() {
+		stockKnown := w.stockKnown
+		w.apply(move)
+		if w.search() {
+			return true
+		}
+		w.game.Undo()
+		w.path = w.path[:len(w.path)-1]
+		w.stockKnown = stockKnown
+		if atomic.LoadInt32(&w.stopped) == 1 {
+			atomic.StoreInt32(&w.interrupted, 1)
+			return false
+		}
+		if atomic.LoadInt32(&w.exhausted) == 1 {
+			return false
+		}
+	}
+	return false
+}
+
+// split expands the top of the search tree breadth first until there are at least n lines to hand out to workers,
+// and returns the move sequence leading to each of them.
+func (w *solverWorker) split(n int) [][]Move {
+	branches := [][]Move{nil}
+	for len(branches) > 0 && len(branches) < n {
+		branch := branches[0]
+		branches = branches[1:]
+		worker := &solverWorker{solverSearch: w.solverSearch, game: searchCopy(w.game), stockKnown: w.stockKnown}
+		for _, move := range branch {
+			worker.apply(move)
+		}
+		if worker.game.IsSolved() {
+			return [][]Move{branch}
+		}
+		if !worker.visit() {
+			return branches
+		}
+		for _, move := range worker.candidateMoves() {
+			branches = append(branches, append(append([]Move{}, branch...), move))
+		}
+	}
+	return branches
+}
+
+// candidateMoves orders the legal moves best first using the hint ranking.  A safe foundation move is always at least
+// as good as anything else, so it's the only candidate when there is one.  Kings are never moved from the bottom of
+// one column to another empty column, and in hidden mode no move may turn up an unseen card.
+func (w *solverWorker) candidateMoves() []Move {
+	type rankedMove struct {
+		move Move
+		rank int
+	}
+	var ranked []rankedMove
+	for _, move := range w.game.LegalMoves() {
+		if w.Hidden && w.revealsUnknown(move) {
+			atomic.StoreInt32(&w.unknown, 1)
+			continue
+		}
+		if w.isSafeFoundationMove(move) {
+			return []Move{move}
+		}
+		if move.Type == MoveTableauTableau && move.CardNum == 0 &&
+			w.game.Tableau.Piles[move.FromPile][0].Pip == pip.King {
+			continue
+		}
+		rank, _ := w.game.rankMove(move)
+		ranked = append(ranked, rankedMove{move, rank})
+	}
+	sort.SliceStable(ranked, func(i, j int) bool { return ranked[i].rank > ranked[j].rank })
+
+	moves := make([]Move, 0, len(ranked))
+	for _, r := range ranked {
+		moves = append(moves, r.move)
+	}
+	return moves
+}
+
+func (w *solverWorker) isSafeFoundationMove(move Move) bool {
+	if move.Type != MoveWasteFoundation && move.Type != MoveTableauFoundation {
+		return false
+	}
+	return w.game.Foundation.isSafe(*w.game.movingCard(move))
+}
+
+func (w *solverWorker) revealsUnknown(move Move) bool {
+	switch move.Type {
+	case MoveDeal:
+		return !w.stockKnown && w.game.Stock.Remaining() > 0
+	case MoveTableauFoundation, MoveTableauTableau:
+		return w.game.revealsCard(move)
+	}
+	return false
+}
+
+// visitKey identifies a position for duplicate detection.  Columns that only differ in position lead to the same
+// outcomes, so they're the same position to the solver.
+type visitKey struct {
+	hash       uint64
+	stockKnown bool
+}
@@ -0,0 +1,242 @@
+package solitaire
+
+import (
+	"context"
+	"fmt"
+	"strconv"
+	"strings"
+	"testing"
+	"time"
+)
+
+// stockAcesLayout has four alternating runs from king to two on the tableau, with the aces waiting in the stock.
+const stockAcesLayout = `
+stock: |A♥ |A♣ |A♦ |A♠
+0: K♥ Q♠ J♦ 10♣ 9♥ 8♠ 7♦ 6♣ 5♥ 4♠ 3♦ 2♣
+1: K♣ Q♥ J♠ 10♦ 9♣ 8♥ 7♠ 6♦ 5♣ 4♥ 3♠ 2♦
+2: K♦ Q♣ J♥ 10♠ 9♦ 8♣ 7♥ 6♠ 5♦ 4♣ 3♥ 2♠
+3: K♠ Q♦ J♣ 10♥ 9♠ 8♦ 7♣ 6♥ 5♠ 4♦ 3♣ 2♥
+4:
+5:
+6:
+`
+
+func TestSolver_SolveThoughtful(t *testing.T) {
+	k := parseTestLayout(t, stockAcesLayout)
+	before := k.Clone()
+	solution := (&Solver{}).Solve(k)
+	if solution.Result != SolverSolved {
+		t.Fatalf("The game should be solved, got %s", solution.Result)
+	}
+	if !k.Equal(before, HashOptions{}) || len(k.UndoStack) != 0 || k.Score != 0 {
+		t.Error("Solve should leave the game as it found it")
+	}
+	for _, move := range solution.Moves {
+		if err := k.Apply(move); err != nil {
+			t.Fatalf("%s should be legal: %s", move, err)
+		}
+	}
+	if !k.IsSolved() {
+		t.Error("Playing the solution should solve the game")
+	}
+}
+
+func TestSolver_SolveHidden(t *testing.T) {
+	k := parseTestLayout(t, stockAcesLayout)
+	if solution := (&Solver{Hidden: true}).Solve(k); solution.Result != SolverUnknown || !solution.Unseen {
+		t.Errorf("Unseen stock cards should make the result unknown, got %s", solution.Result)
+	}
+	// cards that have been through the waste are known
+	k = parseTestLayout(t, strings.Replace(stockAcesLayout, "stock: |A♥ |A♣ |A♦ |A♠", "waste: A♥ A♣ A♦ A♠", 1))
+	if result := (&Solver{Hidden: true}).Solve(k).Result; result != SolverSolved {
+		t.Errorf("A game with no unseen cards should be solved, got %s", result)
+	}
+}
+
+// unsolvableLayout is a deal with no move but dealing: the aces and queens are all face down, so nothing in the stock
+// can go anywhere and the kings and twos on top can't move either.
+const unsolvableLayout = `
+stock: |2♦ |6♣ |6♥ |6♦ |7♠ |7♣ |7♥ |7♦ |8♠ |8♣ |8♥ |8♦ |9♠ |9♣ |9♥ |9♦ |10♠ |10♣ |10♥ |10♦ |J♠ |J♣ |J♥ |J♦
+0: K♠
+1: |A♠ K♣
+2: |A♣ |A♥ K♥
+3: |A♦ |Q♥ |Q♦ K♦
+4: |Q♠ |Q♣ |3♠ |3♣ 2♠
+5: |3♥ |3♦ |4♠ |4♣ |4♥ 2♣
+6: |4♦ |5♠ |5♣ |5♥ |5♦ |6♠ 2♥
+`
+
+func TestSolver_SolveUnsolvable(t *testing.T) {
+	k, err := ParseKlondikeLayout(unsolvableLayout)
+	if err != nil {
+		t.Fatal(err)
+	}
+	solution := (&Solver{}).Solve(k)
+	if solution.Result != SolverUnsolvable {
+		t.Errorf("A deal with no way forward should be unsolvable, got %s", solution.Result)
+	}
+	if solution.Moves != nil {
+		t.Error("An unsolvable game should have no moves")
+	}
+	if result := (&Solver{Hidden: true}).Solve(k).Result; result != SolverUnknown {
+		t.Errorf("Unseen cards should make the hidden result unknown, got %s", result)
+	}
+}
+
+func TestSolver_SolveSeeded(t *testing.T) {
+	solution := (&Solver{}).Solve(NewSeededKlondikeGame(1))
+	if solution.Result != SolverSolved {
+		t.Errorf("Seed 1 should be solved when every card is known, got %s", solution.Result)
+	}
+}
+
+func TestSolver_SolveNodeBudget(t *testing.T) {
+	k := NewSeededKlondikeGame(1)
+	before := k.Clone()
+	solution := (&Solver{MaxNodes: 10}).Solve(k)
+	if solution.Result != SolverUnknown {
+		t.Errorf("A tiny node budget should give an unknown result, got %s", solution.Result)
+	}
+	if solution.Nodes != 10 {
+		t.Errorf("The solver should have searched exactly 10 nodes, not %d", solution.Nodes)
+	}
+	if !k.Equal(before, HashOptions{}) || len(k.UndoStack) != 0 {
+		t.Error("Solve should leave the game as it found it")
+	}
+}
+
+// replayCommand runs a script line through the Select* methods the same way the klondike CLI does.
+func replayCommand(k *KlondikeGame, line string) error {
+	fields := strings.Fields(line)
+	var args []int
+	for _, field := range fields[1:] {
+		if arg, err := strconv.Atoi(field); err == nil {
+			args = append(args, arg)
+		}
+	}
+	switch fields[0] {
+	case "d":
+		return k.Deal()
+	case "autoplay":
+		k.AutoPlay = fields[1] == "on"
+		return nil
+	case "w":
+		return k.SelectWaste(args...)
+	case "t":
+		return k.SelectTableau(args[0], args[1:]...)
+	case "f":
+		for suit, command := range suitCommands {
+			if command == fields[1] {
+				return k.SelectFoundation(suit, args...)
+			}
+		}
+	}
+	return fmt.Errorf("unknown command %q", line)
+}
+
+func TestSolution_Script(t *testing.T) {
+	k := parseTestLayout(t, stockAcesLayout)
+	solution := (&Solver{}).Solve(k)
+	lines := strings.Split(strings.TrimSpace(solution.Script()), "\n")
+	if len(lines) != len(solution.Moves)+1 || lines[0] != "autoplay off" {
+		t.Fatalf("The script should turn autoplay off and then have one line per move, got %d lines for %d moves",
+			len(lines), len(solution.Moves))
+	}
+	lines = lines[1:]
+	for i, line := range lines {
+		if err := replayCommand(k, line); err != nil {
+			t.Fatalf("%q should replay: %s", line, err)
+		}
+		expected := parseTestLayout(t, stockAcesLayout)
+		for _, move := range solution.Moves[:i+1] {
+			expected.Apply(move)
+		}
+		if !k.Equal(expected, HashOptions{}) {
+			t.Fatalf("%q should replay %s", line, solution.Moves[i])
+		}
+	}
+	if !k.IsSolved() {
+		t.Error("Replaying the script should solve the game")
+	}
+}
+
+func TestSolution_ScriptAutoPlay(t *testing.T) {
+	for _, k := range []*KlondikeGame{parseTestLayout(t, stockAcesLayout), NewSeededKlondikeGame(1)} {
+		k.AutoPlay = true
+		solution := (&Solver{}).Solve(k)
+		if solution.Result != SolverSolved {
+			t.Fatalf("The game should be solved, got %s", solution.Result)
+		}
+		for _, line := range strings.Split(strings.TrimSpace(solution.Script()), "\n") {
+			if err := replayCommand(k, line); err != nil {
+				t.Fatalf("%q should replay with autoplay on: %s", line, err)
+			}
+		}
+		if !k.IsSolved() {
+			t.Error("Replaying the script with autoplay on should solve the game")
+		}
+	}
+}
+
+func TestSolver_SolveParallel(t *testing.T) {
+	k := parseTestLayout(t, stockAcesLayout)
+	solution := (&Solver{Workers: 4}).Solve(k)
+	if solution.Result != SolverSolved {
+		t.Fatalf("The game should be solved, got %s", solution.Result)
+	}
+	for _, move := range solution.Moves {
+		if err := k.Apply(move); err != nil {
+			t.Fatalf("%s should be legal: %s", move, err)
+		}
+	}
+	if !k.IsSolved() {
+		t.Error("Playing the solution should solve the game")
+	}
+
+	k = NewSeededKlondikeGame(1)
+	before := k.Clone()
+	solution = (&Solver{Workers: 4, MaxNodes: 1000}).Solve(k)
+	if solution.Result == SolverUnsolvable {
+		t.Error("A search cut short by its node budget can't prove a game unsolvable")
+	}
+	if solution.Nodes > 1000 {
+		t.Errorf("The solver should have searched at most 1000 nodes, not %d", solution.Nodes)
+	}
+	if !k.Equal(before, HashOptions{}) || len(k.UndoStack) != 0 {
+		t.Error("Solve should leave the game as it found it")
+	}
+}
+
+func TestSolver_SolveContextCancelled(t *testing.T) {
+	ctx, cancel := context.WithCancel(context.Background())
+	cancel()
+	for _, workers := range []int{1, 4} {
+		solution := (&Solver{Workers: workers}).SolveContext(ctx, NewSeededKlondikeGame(1))
+		if solution.Result != SolverUnknown {
+			t.Errorf("A cancelled search should be unknown, got %s", solution.Result)
+		}
+	}
+}
+
+func TestSolver_Progress(t *testing.T) {
+	var reports []SolverProgress
+	solver := Solver{
+		MaxNodes:         2000,
+		ProgressInterval: time.Millisecond,
+		Progress:         func(progress SolverProgress) { reports = append(reports, progress) },
+	}
+	solution := solver.Solve(NewSeededKlondikeGame(1))
+	if len(reports) == 0 {
+		t.Fatal("Progress should have been reported at least once")
+	}
+	last := reports[len(reports)-1]
+	if last.Nodes != solution.Nodes {
+		t.Errorf("The last report should count all %d nodes, not %d", solution.Nodes, last.Nodes)
+	}
+	for i := 1; i < len(reports); i++ {
+		if reports[i].Nodes < reports[i-1].Nodes || reports[i].Depth < reports[i-1].Depth ||
+			reports[i].BestFoundation < reports[i-1].BestFoundation {
+			t.Error("Progress should never go backwards")
+		}
+	}
+}
@@ -1,7 +1,7 @@
 package solitaire
 
 import (
-	"errors"
+	"fmt"
 	"github.com/jamesboehmer/gopatience/pkg/cards"
 	"github.com/jamesboehmer/gopatience/pkg/cards/pip"
 	"github.com/jamesboehmer/gopatience/pkg/games/util"
@@ -10,6 +10,7 @@ import (
 type Tableau struct {
 	util.Undoable
 	Piles [][]*cards.Card
+	hash  uint64
 }
 
 func NewTableau(size int, deck *cards.Deck) *Tableau {
@@ -29,62 +30,95 @@ func NewTableau(size int, deck *cards.Deck) *Tableau {
 			}
 		}
 	}
+	tableau.hash = tableau.computeHash()
 	return tableau
 }
 
-func (t *Tableau) Put(cards []*cards.Card, pileNum int) error {
+// fits reports whether the card can go on the pile, by the same rules as checkPut but without saying why not.
+func (t *Tableau) fits(card *cards.Card, pileNum int) bool {
+	if !card.Revealed || pileNum < 0 || pileNum > len(t.Piles)-1 {
+		return false
+	}
+	pile := t.Piles[pileNum]
+	if len(pile) == 0 {
+		return card.Pip == pip.King
+	}
+	return buildsOn(card, pile[len(pile)-1])
+}
+
+func (t *Tableau) checkPut(cards []*cards.Card, pileNum int) error {
 	if !cards[0].Revealed {
-		return errors.New("concealed cards may not be put on the tableau")
+		return ErrConcealed
 	}
 	if pileNum < 0 || pileNum > len(t.Piles)-1 {
-		return errors.New("invalid pile number")
+		return ErrInvalidPile
+	}
+	if t.fits(cards[0], pileNum) {
+		return nil
 	}
+	rejection := &Rejection{Card: *cards[0], Pile: fmt.Sprintf("tableau %d", pileNum)}
 	if len(t.Piles[pileNum]) == 0 {
-		if cards[0].Pip == pip.King {
-			t.Piles[pileNum] = append(t.Piles[pileNum], cards...)
-			t.UndoStack = append(t.UndoStack, util.UndoAction{
-				Function: t.undoPut,
-				Args:     []interface{}{pileNum, len(cards)},
-			})
-			return nil
-		} else {
-			return errors.New("only kings may be built on empty tableau piles")
-		}
+		rejection.Reasons = append(rejection.Reasons, "only kings on empty piles")
+		return rejection
+	}
+	topCard := *t.Piles[pileNum][len(t.Piles[pileNum])-1]
+	if cards[0].Suit.Color() == topCard.Suit.Color() {
+		rejection.Reasons = append(rejection.Reasons, "same color")
 	}
-	topCard := t.Piles[pileNum][len(t.Piles[pileNum])-1]
-	if cards[0].Suit.Color() == topCard.Suit.Color() || PipValue[cards[0].Pip] != PipValue[topCard.Pip]-1 {
-		return errors.New("tableau cards must be built in descending order with alternate colors")
+	if PipValue[cards[0].Pip] != PipValue[topCard.Pip]-1 {
+		rejection.Reasons = append(rejection.Reasons, "not one rank lower")
+	}
+	rejection.Target = &topCard
+	return rejection
+}
 
-	} else {
-		t.Piles[pileNum] = append(t.Piles[pileNum], cards...)
-		t.UndoStack = append(t.UndoStack, util.UndoAction{
-			Function: t.undoPut,
-			Args:     []interface{}{pileNum, len(cards)},
-		})
-		return nil
+func (t *Tableau) Put(cards []*cards.Card, pileNum int) error {
+	if err := t.checkPut(cards, pileNum); err != nil {
+		return located(err, "tableau %d", pileNum)
 	}
+	t.Piles[pileNum] = append(t.Piles[pileNum], cards...)
+	t.hashCards(pileNum, len(t.Piles[pileNum])-len(cards))
+	t.UndoStack = append(t.UndoStack, util.UndoAction{
+		Function: t.undoPut,
+		Args:     []interface{}{pileNum, len(cards)},
+	})
+	return nil
 }
 
 func (t *Tableau) undoPut(args ...interface{}) error {
 	pileNum, numCards := args[0].(int), args[1].(int)
-	for i := numCards; i > 0; i-- {
-		t.Piles[pileNum] = t.Piles[pileNum][:len(t.Piles[pileNum])-numCards]
-	}
+	t.hashCards(pileNum, len(t.Piles[pileNum])-numCards)
+	t.Piles[pileNum] = t.Piles[pileNum][:len(t.Piles[pileNum])-numCards]
 	return nil
 }
 
-func (t *Tableau) Get(pileNum int, cardNum int) ([]*cards.Card, error) {
+// canGet reports whether the cards from cardNum up can be taken off the pile, by the same rules as checkGet.
+func (t *Tableau) canGet(pileNum int, cardNum int) bool {
+	return pileNum >= 0 && pileNum < len(t.Piles) && cardNum >= 0 && cardNum < len(t.Piles[pileNum]) &&
+		t.Piles[pileNum][cardNum].Revealed
+}
+
+func (t *Tableau) checkGet(pileNum int, cardNum int) error {
 	if pileNum < 0 || pileNum > len(t.Piles)-1 {
-		return nil, errors.New("invalid pile number")
+		return ErrInvalidPile
 	}
 	if cardNum < 0 || cardNum > len(t.Piles[pileNum])-1 {
-		return nil, errors.New("invalid card number")
+		return ErrInvalidCard
 	}
 	if !t.Piles[pileNum][cardNum].Revealed {
-		return nil, errors.New("card is concealed")
+		return ErrConcealed
 	}
+	return nil
+}
 
-	cards := t.Piles[pileNum][cardNum:]
+func (t *Tableau) Get(pileNum int, cardNum int) ([]*cards.Card, error) {
+	if err := t.checkGet(pileNum, cardNum); err != nil {
+		return nil, located(err, "tableau %d card %d", pileNum, cardNum)
+	}
+
+	// copy the selection so later puts on this pile can't overwrite it through the shared backing array
+	cards := append([]*cards.Card{}, t.Piles[pileNum][cardNum:]...)
+	t.hashCards(pileNum, cardNum)
 	t.Piles[pileNum] = t.Piles[pileNum][:cardNum]
 	revealed := t.reveal(pileNum)
 	t.UndoStack = append(t.UndoStack, util.UndoAction{
@@ -101,13 +135,16 @@ func (t *Tableau) undoGet(args ...interface{}) error {
 		t.conceal(pileNum)
 	}
 	t.Piles[pileNum] = append(t.Piles[pileNum], cards...)
+	t.hashCards(pileNum, len(t.Piles[pileNum])-len(cards))
 	return nil
 }
 
 func (t *Tableau) reveal(pileNum int) bool {
 	pile := t.Piles[pileNum]
 	if len(pile) > 0 && !pile[len(pile)-1].Revealed {
+		t.hashCards(pileNum, len(pile)-1)
 		pile[len(pile)-1].Reveal()
+		t.hashCards(pileNum, len(pile)-1)
 		return true
 	}
 	return false
@@ -117,9 +154,20 @@ func (t *Tableau) reveal(pileNum int) bool {
 func (t *Tableau) conceal(pileNum int) bool {
 	pile := t.Piles[pileNum]
 	if len(pile) > 0 && pile[len(pile)-1].Revealed {
+		t.hashCards(pileNum, len(pile)-1)
 		pile[len(pile)-1].Conceal()
+		t.hashCards(pileNum, len(pile)-1)
 		return true
 	}
 	return false
 
 }
+
+// hashCards XORs the keys of the cards in the pile from cardNum up into the hash, which adds them if they're new and
+// takes them out if they're about to go.
+func (t *Tableau) hashCards(pileNum int, cardNum int) {
+	pile := t.Piles[pileNum]
+	for ; cardNum < len(pile); cardNum++ {
+		t.hash ^= tableauKey(pile, cardNum)
+	}
+}
@@ -228,3 +228,16 @@ func TestTableau_undoPut(t *testing.T) {
 		}
 	}
 }
+
+func TestTableau_undoPutMultipleCards(t *testing.T) {
+	tableau := NewTableau(7, nil)
+	tableau.Put([]*cards.Card{{Pip: pip.King, Suit: suit.Spades, Revealed: true}}, 0)
+	tableau.Put([]*cards.Card{
+		{Pip: pip.Queen, Suit: suit.Hearts, Revealed: true},
+		{Pip: pip.Jack, Suit: suit.Clubs, Revealed: true},
+	}, 0)
+	tableau.Undo()
+	if len(tableau.Piles[0]) != 1 {
+		t.Error("Undoing a 2 card put should only remove 2 cards")
+	}
+}
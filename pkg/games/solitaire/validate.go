@@ -0,0 +1,136 @@
+package solitaire
+
+import (
+	"fmt"
+	"github.com/jamesboehmer/gopatience/pkg/cards"
+	"github.com/jamesboehmer/gopatience/pkg/cards/pip"
+	"github.com/jamesboehmer/gopatience/pkg/cards/suit"
+	"sort"
+	"strings"
+)
+
+// A ValidationError lists every broken invariant Validate found.
+type ValidationError struct {
+	Problems []string
+}
+
+func (e *ValidationError) Error() string {
+	return "invalid game: " + strings.Join(e.Problems, "; ")
+}
+
+// Validate checks the invariants every klondike position has to keep: the game holds exactly the 52 cards of one
+// deck, stock cards are face down and waste cards face up, each foundation pile is its own suit from the ace up, and
+// each tableau pile has its face-down cards at the bottom, a face-up top card, and a face-up run built down in
+// alternating colors.  It returns a *ValidationError listing every problem, or nil.
+func (k *KlondikeGame) Validate() error {
+	problems := []string{}
+	problem := func(format string, args ...interface{}) {
+		problems = append(problems, fmt.Sprintf(format, args...))
+	}
+
+	seen := map[cards.Card]int{}
+	count := func(card cards.Card) {
+		card.Revealed = false
+		seen[card]++
+	}
+	for _, card := range k.Stock.Cards {
+		if card.Revealed {
+			problem("stock card %s is face up", &card)
+		}
+		count(card)
+	}
+	for _, card := range k.Waste {
+		if !card.Revealed {
+			problem("waste card %s is face down", &card)
+		}
+		count(card)
+	}
+	for _, suit := range klondikeSuits {
+		for cardNum, card := range k.Foundation.Piles[suit] {
+			if card.Suit != suit || PipValue[card.Pip] != cardNum+1 {
+				problem("foundation %s card %d is %s", suit, cardNum, &card)
+			}
+			if !card.Revealed {
+				problem("foundation %s card %d is face down", suit, cardNum)
+			}
+			count(card)
+		}
+	}
+	for foundationSuit := range k.Foundation.Piles {
+		if _, found := suit.Suits[string(foundationSuit)]; !found {
+			problem("unknown foundation suit %q", foundationSuit)
+		}
+	}
+	for pileNum, pile := range k.Tableau.Piles {
+		for cardNum, card := range pile {
+			if card == nil {
+				problem("tableau %d card %d is missing", pileNum, cardNum)
+				continue
+			}
+			count(*card)
+			if cardNum == 0 {
+				continue
+			}
+			below := pile[cardNum-1]
+			switch {
+			case below == nil:
+			case !card.Revealed && below.Revealed:
+				problem("tableau %d card %d is face down on a face-up card", pileNum, cardNum)
+			case card.Revealed && below.Revealed && !buildsOn(card, below):
+				problem("tableau %d card %d %s is not built on %s", pileNum, cardNum, card, below)
+			}
+		}
+		if len(pile) > 0 && pile[len(pile)-1] != nil && !pile[len(pile)-1].Revealed {
+			problem("tableau %d top card is face down", pileNum)
+		}
+	}
+
+	for _, suit := range suit.All {
+		for _, pip := range pip.All {
+			card := cards.Card{Pip: pip, Suit: suit}
+			name := cards.Card{Pip: pip, Suit: suit, Revealed: true}
+			switch seen[card] {
+			case 0:
+				problem("%s is missing", &name)
+			case 1:
+			default:
+				problem("%s appears %d times", &name, seen[card])
+			}
+			delete(seen, card)
+		}
+	}
+	var strangers []string
+	for card := range seen {
+		card.Revealed = true
+		strangers = append(strangers, card.String())
+	}
+	sort.Strings(strangers)
+	for _, card := range strangers {
+		problem("%s is not a klondike card", card)
+	}
+
+	if len(problems) > 0 {
+		return &ValidationError{Problems: problems}
+	}
+	return nil
+}
+
+// isValid reports whether the game is valid and its hash is up to date, which mustStayValid then checks are kept.
+// Games whose piles have been changed directly may not be.
+func (k *KlondikeGame) isValid() bool {
+	return k.Validate() == nil && !k.hashIsStale()
+}
+
+func (k *KlondikeGame) hashIsStale() bool {
+	return k.Tableau.hash != k.Tableau.computeHash() || k.Foundation.hash != k.Foundation.computeHash()
+}
+
+// mustStayValid panics if what was just done to a valid game broke it.  Only debug builds call it.
+func (k *KlondikeGame) mustStayValid(what string) {
+	if err := k.Validate(); err != nil {
+		panic(fmt.Sprintf("%s broke the game: %s", what, err))
+	}
+	if k.hashIsStale() {
+		panic(fmt.Sprintf("%s left the game's hash out of date", what))
+	}
+}
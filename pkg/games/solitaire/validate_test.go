@@ -0,0 +1,82 @@
+package solitaire
+
+import (
+	"github.com/jamesboehmer/gopatience/pkg/cards"
+	"github.com/jamesboehmer/gopatience/pkg/cards/suit"
+	"strings"
+	"testing"
+)
+
+func TestKlondikeGame_Validate(t *testing.T) {
+	k := NewSeededKlondikeGame(7)
+	if err := k.Validate(); err != nil {
+		t.Errorf("A new game should be valid, got %s", err)
+	}
+	for i := 0; i < 30; i++ {
+		k.Deal()
+	}
+	if err := k.Validate(); err != nil {
+		t.Errorf("Dealing through the stock should keep the game valid, got %s", err)
+	}
+}
+
+func TestKlondikeGame_ValidateCards(t *testing.T) {
+	k := NewSeededKlondikeGame(7)
+	missing, repeated := k.Stock.Cards[0], k.Stock.Cards[1]
+	missing.Revealed, repeated.Revealed = true, true
+	k.Stock.Cards[0] = k.Stock.Cards[1]
+	k.Waste = []cards.Card{{Revealed: true}}
+	problems := map[string]bool{}
+	for _, problem := range k.Validate().(*ValidationError).Problems {
+		problems[problem] = true
+	}
+	for _, problem := range []string{
+		missing.String() + " is missing",
+		repeated.String() + " appears 2 times",
+		"* is not a klondike card",
+	} {
+		if !problems[problem] {
+			t.Errorf("Expected problem %q, got %v", problem, problems)
+		}
+	}
+	if len(problems) != 3 {
+		t.Errorf("Expected 3 problems, got %v", problems)
+	}
+}
+
+func TestKlondikeGame_ValidateFoundation(t *testing.T) {
+	k := NewSeededKlondikeGame(7)
+	k.Stock.Cards = k.Stock.Cards[:0]
+	k.Foundation.Piles[suit.Hearts] = []cards.Card{{Pip: "2", Suit: suit.Hearts, Revealed: true}}
+	problems := k.Validate().(*ValidationError).Problems
+	if problems[0] != "foundation ♥ card 0 is 2♥" {
+		t.Errorf("Foundation piles should start with the ace, got %q", problems[0])
+	}
+}
+
+func TestKlondikeGame_ValidateTableau(t *testing.T) {
+	k := parseTestLayout(t, "0: |K♠ 9♣\n1: |A♥ 10♥\n2: |10♦ 9♦")
+	// turn the face-down cards of piles 1 and 2 into ones no layout would parse
+	pile := k.Tableau.Piles[1]
+	pile[0], pile[1] = pile[1], pile[0]
+	k.Tableau.Piles[2][0].Reveal()
+	problems := k.Validate().(*ValidationError).Problems
+	expected := []string{
+		"tableau 1 card 1 is face down on a face-up card",
+		"tableau 1 top card is face down",
+		"tableau 2 card 1 9♦ is not built on 10♦",
+	}
+	for i, problem := range expected {
+		if problems[i] != problem {
+			t.Errorf("Expected problem %q, got %q", problem, problems[i])
+		}
+	}
+}
+
+func TestLoadKlondikeGame_Validates(t *testing.T) {
+	k := NewSeededKlondikeGame(7)
+	k.Stock.Cards = k.Stock.Cards[1:]
+	if _, err := LoadKlondikeGame(strings.NewReader(signedSave(t, k))); err == nil {
+		t.Error("Loading a save with a missing card should fail")
+	}
+}